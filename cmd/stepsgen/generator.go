@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"maps"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	stepsPkgPath = "github.com/domahidizoltan/go-steps"
+	annotation   = "//stepsgen:pipeline"
+)
+
+var (
+	errNoPipelines           = errors.New("no annotated pipelines found")
+	errInvalidPipeline       = errors.New("invalid pipeline")
+	errUnsupportedStep       = errors.New("unsupported step")
+	errUnknownStepType       = errors.New("unknown step type")
+	errIncompatibleInArgType = errors.New("incompatible input argument type")
+)
+
+type (
+	generator struct {
+		fset     *token.FileSet
+		src      []byte
+		file     *ast.File
+		stepsPkg string
+		imports  map[string]string
+		used     map[string]bool
+	}
+
+	pipeline struct {
+		name       string
+		params     string
+		inType     string
+		outType    string
+		steps      []step
+		aggregator *step
+	}
+
+	step struct {
+		name    string
+		inType  string
+		outType string
+		fn      string
+		// arg is the count of Take and Skip, or the initial value of Fold
+		arg string
+	}
+)
+
+// generate returns the source of the typed loops generated from the annotated pipelines of a Go source file
+func generate(filename string, src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	g := generator{
+		fset:    fset,
+		src:     src,
+		file:    file,
+		imports: map[string]string{},
+		used:    map[string]bool{},
+	}
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		name := path.Base(importPath)
+		if importPath == stepsPkgPath {
+			name = "steps"
+			g.stepsPkg = name
+		}
+		if spec.Name != nil {
+			name = spec.Name.Name
+			if importPath == stepsPkgPath {
+				g.stepsPkg = name
+			}
+		}
+		g.imports[name] = g.source(spec)
+	}
+	if len(g.stepsPkg) == 0 {
+		return nil, fmt.Errorf("%w: %s is not imported", errNoPipelines, stepsPkgPath)
+	}
+
+	pipelines, err := g.parsePipelines()
+	if err != nil {
+		return nil, err
+	}
+	if len(pipelines) == 0 {
+		return nil, errNoPipelines
+	}
+
+	out := bytes.Buffer{}
+	fmt.Fprintf(&out, "// Code generated by stepsgen. DO NOT EDIT.\n\npackage %s\n", file.Name.Name)
+	if len(g.used) > 0 {
+		out.WriteString("\nimport (\n")
+		for _, name := range slices.Sorted(maps.Keys(g.used)) {
+			fmt.Fprintf(&out, "\t%s\n", g.imports[name])
+		}
+		out.WriteString(")\n")
+	}
+	for _, p := range pipelines {
+		p.writeTo(&out)
+	}
+
+	return format.Source(out.Bytes())
+}
+
+func (g *generator) parsePipelines() ([]pipeline, error) {
+	var pipelines []pipeline
+	for _, decl := range g.file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !isAnnotated(d.Doc) {
+				continue
+			}
+			p, err := g.parseFuncPipeline(d)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", d.Name.Name, err)
+			}
+			pipelines = append(pipelines, p)
+		case *ast.GenDecl:
+			if d.Tok != token.VAR {
+				continue
+			}
+			for _, s := range d.Specs {
+				spec := s.(*ast.ValueSpec)
+				if !isAnnotated(d.Doc) && !isAnnotated(spec.Doc) {
+					continue
+				}
+				if len(spec.Names) != 1 || len(spec.Values) != 1 {
+					return nil, fmt.Errorf("%w: only single variable declarations are supported", errInvalidPipeline)
+				}
+				name := spec.Names[0].Name
+				p, err := g.parsePipeline(name, spec.Values[0])
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+				pipelines = append(pipelines, p)
+			}
+		}
+	}
+	return pipelines, nil
+}
+
+func isAnnotated(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == annotation {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFuncPipeline parses a function returning the pipeline. The parameters of the function are kept by the generated function.
+func (g *generator) parseFuncPipeline(fn *ast.FuncDecl) (pipeline, error) {
+	if fn.Recv != nil || fn.Type.TypeParams != nil || fn.Body == nil || len(fn.Body.List) != 1 {
+		return pipeline{}, fmt.Errorf("%w: the function must return the pipeline in a single statement", errInvalidPipeline)
+	}
+	ret, ok := fn.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return pipeline{}, fmt.Errorf("%w: the function must return the pipeline in a single statement", errInvalidPipeline)
+	}
+
+	p, err := g.parsePipeline(fn.Name.Name, ret.Results[0])
+	if err != nil {
+		return pipeline{}, err
+	}
+	if params := fn.Type.Params; len(params.List) > 0 {
+		g.markUsed(params)
+		p.params = string(g.src[g.offset(params.Opening)+1 : g.offset(params.Closing)])
+	}
+	return p, nil
+}
+
+// parsePipeline parses the Steps(...) and the Steps(...).Aggregate(...) expressions
+func (g *generator) parsePipeline(name string, expr ast.Expr) (pipeline, error) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return pipeline{}, fmt.Errorf("%w: expected Steps(...) call", errInvalidPipeline)
+	}
+
+	var aggregatorExpr ast.Expr
+	if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Aggregate" {
+		if stepsCall, ok := sel.X.(*ast.CallExpr); ok && len(call.Args) == 1 {
+			aggregatorExpr = call.Args[0]
+			call = stepsCall
+		}
+	}
+
+	fnName, _, ok := g.stepsFunc(call.Fun)
+	switch {
+	case ok && fnName == "Aggregate" && len(call.Args) == 1:
+		aggregatorExpr = call.Args[0]
+		call = &ast.CallExpr{}
+	case !ok || fnName != "Steps":
+		return pipeline{}, fmt.Errorf("%w: expected Steps(...) call", errInvalidPipeline)
+	}
+
+	p := pipeline{name: name}
+	for pos, arg := range call.Args {
+		s, err := g.parseStep(arg, pos, false)
+		if err != nil {
+			return pipeline{}, err
+		}
+		p.steps = append(p.steps, s)
+	}
+	if aggregatorExpr != nil {
+		s, err := g.parseStep(aggregatorExpr, len(call.Args), true)
+		if err != nil {
+			return pipeline{}, err
+		}
+		p.aggregator = &s
+	}
+
+	all := p.steps
+	if p.aggregator != nil {
+		all = append(slices.Clone(all), *p.aggregator)
+	}
+	if len(all) == 0 {
+		return pipeline{}, fmt.Errorf("%w: no steps", errInvalidPipeline)
+	}
+	p.inType = all[0].inType
+	p.outType = all[0].inType
+	for pos, s := range all {
+		if s.inType != p.outType {
+			return pipeline{}, fmt.Errorf("%w [%s:%d]: [%s!=%s]", errIncompatibleInArgType, s.name, pos+1, p.outType, s.inType)
+		}
+		p.outType = s.outType
+	}
+	return p, nil
+}
+
+// parseStep parses a built-in step (or aggregator) call. The types are taken from the explicit type arguments,
+// or from the signature of the function literal.
+func (g *generator) parseStep(expr ast.Expr, pos int, aggregator bool) (step, error) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return step{}, fmt.Errorf("%w [%s:%d]", errUnsupportedStep, g.source(expr), pos+1)
+	}
+	name, typeArgs, ok := g.stepsFunc(call.Fun)
+	if !ok {
+		return step{}, fmt.Errorf("%w [%s:%d]", errUnsupportedStep, g.source(call.Fun), pos+1)
+	}
+
+	s := step{name: name}
+	var fnArg ast.Expr
+	switch {
+	case !aggregator && slices.Contains([]string{"Map", "Filter", "TakeWhile", "SkipWhile", "Do"}, name) && len(call.Args) == 1:
+		fnArg = call.Args[0]
+	case !aggregator && slices.Contains([]string{"Take", "Skip"}, name) && len(call.Args) == 1:
+		s.arg = g.source(call.Args[0])
+		g.markUsed(call.Args[0])
+	case aggregator && name == "Sum" && len(call.Args) == 0:
+	case aggregator && name == "Reduce" && len(call.Args) == 1:
+		fnArg = call.Args[0]
+	case aggregator && name == "Fold" && len(call.Args) == 2:
+		s.arg = g.source(call.Args[0])
+		g.markUsed(call.Args[0])
+		fnArg = call.Args[1]
+	default:
+		return step{}, fmt.Errorf("%w [%s:%d]", errUnsupportedStep, name, pos+1)
+	}
+
+	types := typeArgs
+	if fnArg != nil {
+		s.fn = g.source(fnArg)
+		g.markUsed(fnArg)
+		if lit, ok := fnArg.(*ast.FuncLit); ok && len(types) == 0 {
+			types = funcLitTypes(lit)
+		}
+	}
+	if len(types) == 0 {
+		return step{}, fmt.Errorf("%w [%s:%d]: use a function literal or explicit type arguments", errUnknownStepType, name, pos+1)
+	}
+	for _, t := range types {
+		g.markUsed(t)
+	}
+
+	s.inType = g.source(types[0])
+	s.outType = s.inType
+	if name == "Map" {
+		if len(types) < 2 {
+			return step{}, fmt.Errorf("%w [%s:%d]: missing output type", errUnknownStepType, name, pos+1)
+		}
+		s.outType = g.source(types[1])
+	}
+	return s, nil
+}
+
+// funcLitTypes returns the type of the first parameter, followed by the type of the first result
+func funcLitTypes(lit *ast.FuncLit) []ast.Expr {
+	var types []ast.Expr
+	if params := lit.Type.Params.List; len(params) > 0 {
+		types = append(types, params[0].Type)
+	}
+	if results := lit.Type.Results; results != nil && len(results.List) > 0 && len(types) > 0 {
+		types = append(types, results.List[0].Type)
+	}
+	return types
+}
+
+// stepsFunc returns the name and the type arguments of a function from the steps package
+func (g *generator) stepsFunc(expr ast.Expr) (string, []ast.Expr, bool) {
+	var typeArgs []ast.Expr
+	switch e := expr.(type) {
+	case *ast.IndexExpr:
+		expr, typeArgs = e.X, []ast.Expr{e.Index}
+	case *ast.IndexListExpr:
+		expr, typeArgs = e.X, e.Indices
+	}
+
+	switch e := expr.(type) {
+	case *ast.SelectorExpr:
+		if pkg, ok := e.X.(*ast.Ident); ok && pkg.Name == g.stepsPkg {
+			return e.Sel.Name, typeArgs, true
+		}
+	case *ast.Ident:
+		if g.stepsPkg == "." {
+			return e.Name, typeArgs, true
+		}
+	}
+	return "", nil, false
+}
+
+// markUsed collects the imports referenced by the code copied to the generated file
+func (g *generator) markUsed(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Obj == nil {
+				if _, ok := g.imports[pkg.Name]; ok {
+					g.used[pkg.Name] = true
+				}
+			}
+		}
+		return true
+	})
+}
+
+func (g *generator) offset(pos token.Pos) int {
+	return g.fset.Position(pos).Offset
+}
+
+func (g *generator) source(node ast.Node) string {
+	return string(g.src[g.offset(node.Pos()):g.offset(node.End())])
+}
+
+// writeTo writes the typed loop of the pipeline.
+// The loop has the same semantics as collecting the transformer output with AsSlice,
+// but it returns the first error instead of passing it to the error handler.
+func (p pipeline) writeTo(out *bytes.Buffer) {
+	params := "in []" + p.inType
+	if len(p.params) > 0 {
+		params = p.params + ", " + params
+	}
+	fmt.Fprintf(out, "\n// %sGenerated is the typed loop generated from the %s pipeline.\n", p.name, p.name)
+	fmt.Fprintf(out, "func %sGenerated(%s) ([]%s, error) {\n", p.name, params, p.outType)
+
+	for i, s := range p.steps {
+		pos := i + 1
+		if len(s.fn) > 0 {
+			fmt.Fprintf(out, "step%d := %s\n", pos, s.fn)
+		}
+		switch s.name {
+		case "Take", "Skip":
+			fmt.Fprintf(out, "var counter%d uint64\n", pos)
+		case "TakeWhile":
+			fmt.Fprintf(out, "taking%d := true\n", pos)
+		case "SkipWhile":
+			fmt.Fprintf(out, "skipping%d := true\n", pos)
+		}
+	}
+
+	errResult := "res"
+	if agg := p.aggregator; agg != nil {
+		errResult = "nil"
+		if len(agg.fn) > 0 {
+			fmt.Fprintf(out, "aggregator := %s\n", agg.fn)
+		}
+		if agg.name == "Fold" {
+			fmt.Fprintf(out, "var agg %s = %s\n", agg.outType, agg.arg)
+		} else {
+			fmt.Fprintf(out, "var agg %s\n", agg.outType)
+		}
+		out.WriteString("var aggregated bool\n")
+	} else {
+		fmt.Fprintf(out, "res := make([]%s, 0, len(in))\n", p.outType)
+	}
+	checkErr := fmt.Sprintf("if err != nil {\nreturn %s, err\n}\n", errResult)
+
+	out.WriteString("for _, v0 := range in {\n")
+	cur := "v0"
+	for i, s := range p.steps {
+		pos := i + 1
+		switch s.name {
+		case "Map":
+			fmt.Fprintf(out, "v%d, err := step%d(%s)\n%s", pos, pos, cur, checkErr)
+			cur = fmt.Sprintf("v%d", pos)
+		case "Filter":
+			fmt.Fprintf(out, "ok%d, err := step%d(%s)\n%s", pos, pos, cur, checkErr)
+			fmt.Fprintf(out, "if !ok%d {\ncontinue\n}\n", pos)
+		case "Do":
+			fmt.Fprintf(out, "if err := step%d(%s); err != nil {\nreturn %s, err\n}\n", pos, cur, errResult)
+		case "Take":
+			fmt.Fprintf(out, "counter%d++\nif counter%d > %s {\ncontinue\n}\n", pos, pos, s.arg)
+		case "Skip":
+			fmt.Fprintf(out, "counter%d++\nif counter%d <= %s {\ncontinue\n}\n", pos, pos, s.arg)
+		case "TakeWhile":
+			fmt.Fprintf(out, "ok%d, err := step%d(%s)\n%s", pos, pos, cur, checkErr)
+			fmt.Fprintf(out, "taking%d = taking%d && ok%d\nif !taking%d {\ncontinue\n}\n", pos, pos, pos, pos)
+		case "SkipWhile":
+			fmt.Fprintf(out, "ok%d, err := step%d(%s)\n%s", pos, pos, cur, checkErr)
+			fmt.Fprintf(out, "skipping%d = skipping%d && ok%d\nif skipping%d {\ncontinue\n}\n", pos, pos, pos, pos)
+		}
+	}
+
+	switch agg := p.aggregator; {
+	case agg == nil:
+		fmt.Fprintf(out, "res = append(res, %s)\n}\nreturn res, nil\n}\n", cur)
+		return
+	case agg.name == "Sum":
+		fmt.Fprintf(out, "agg += %s\n", cur)
+	default:
+		fmt.Fprintf(out, "next, err := aggregator(agg, %s)\n%sagg = next\n", cur, checkErr)
+	}
+	out.WriteString("aggregated = true\n}\n")
+	fmt.Fprintf(out, "if !aggregated {\nreturn []%s{}, nil\n}\nreturn []%s{agg}, nil\n}\n", p.outType, p.outType)
+}
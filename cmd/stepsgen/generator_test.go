@@ -0,0 +1,190 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate_Success(t *testing.T) {
+	src := `package sample
+
+import (
+	"fmt"
+	"strconv"
+
+	gs "github.com/domahidizoltan/go-steps"
+)
+
+// toString is not annotated
+var toString = gs.Steps(gs.Map(func(i int) (string, error) {
+	return fmt.Sprint(i), nil
+}))
+
+//stepsgen:pipeline
+var evenSquares = gs.Steps(
+	gs.Filter(func(i int) (bool, error) {
+		return i%2 == 0, nil
+	}),
+	gs.Map[int, string](square),
+)
+
+//stepsgen:pipeline
+func sumBelow(max int) gs.StepsBranch {
+	return gs.Steps(
+		gs.TakeWhile(func(i int) (bool, error) {
+			return i < max, nil
+		}),
+	).Aggregate(gs.Sum[int]())
+}
+
+func square(i int) (string, error) {
+	return strconv.Itoa(i * i), nil
+}
+`
+	expected := `// Code generated by stepsgen. DO NOT EDIT.
+
+package sample
+
+// evenSquaresGenerated is the typed loop generated from the evenSquares pipeline.
+func evenSquaresGenerated(in []int) ([]string, error) {
+	step1 := func(i int) (bool, error) {
+		return i%2 == 0, nil
+	}
+	step2 := square
+	res := make([]string, 0, len(in))
+	for _, v0 := range in {
+		ok1, err := step1(v0)
+		if err != nil {
+			return res, err
+		}
+		if !ok1 {
+			continue
+		}
+		v2, err := step2(v0)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, v2)
+	}
+	return res, nil
+}
+
+// sumBelowGenerated is the typed loop generated from the sumBelow pipeline.
+func sumBelowGenerated(max int, in []int) ([]int, error) {
+	step1 := func(i int) (bool, error) {
+		return i < max, nil
+	}
+	taking1 := true
+	var agg int
+	var aggregated bool
+	for _, v0 := range in {
+		ok1, err := step1(v0)
+		if err != nil {
+			return nil, err
+		}
+		taking1 = taking1 && ok1
+		if !taking1 {
+			continue
+		}
+		agg += v0
+		aggregated = true
+	}
+	if !aggregated {
+		return []int{}, nil
+	}
+	return []int{agg}, nil
+}
+`
+
+	actual, err := generate("sample.go", []byte(src))
+
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(actual))
+}
+
+func TestGenerate_Imports(t *testing.T) {
+	src := `package sample
+
+import (
+	"strconv"
+	str "strings"
+	"time"
+
+	. "github.com/domahidizoltan/go-steps"
+)
+
+var timeout = time.Second
+
+//stepsgen:pipeline
+var pipeline = Steps(
+	Map(func(s string) (int, error) {
+		return strconv.Atoi(str.TrimSpace(s))
+	}),
+)
+`
+
+	actual, err := generate("sample.go", []byte(src))
+
+	require.NoError(t, err)
+	assert.Contains(t, string(actual), "import (\n\t\"strconv\"\n\tstr \"strings\"\n)\n")
+}
+
+func TestGenerate_Failure(t *testing.T) {
+	for _, sc := range []struct {
+		name          string
+		pipeline      string
+		expectedError error
+		errorMessage  string
+	}{
+		{
+			name:          "no_pipelines",
+			pipeline:      `var pipeline = Steps()`,
+			expectedError: errNoPipelines,
+		}, {
+			name:          "not_steps",
+			pipeline:      "//stepsgen:pipeline\nvar pipeline = []StepWrapper{}",
+			expectedError: errInvalidPipeline,
+		}, {
+			name:          "multiple_statements",
+			pipeline:      "//stepsgen:pipeline\nfunc pipeline() StepsBranch {\n\ts := Steps()\n\treturn s\n}",
+			expectedError: errInvalidPipeline,
+		}, {
+			name:          "empty_steps",
+			pipeline:      "//stepsgen:pipeline\nvar pipeline = Steps()",
+			expectedError: errInvalidPipeline,
+		}, {
+			name:          "unsupported_step",
+			pipeline:      "//stepsgen:pipeline\nvar pipeline = Steps(Take[int](1), Log())",
+			expectedError: errUnsupportedStep,
+			errorMessage:  "pipeline: unsupported step [Log:2]",
+		}, {
+			name:          "unknown_step_type",
+			pipeline:      "//stepsgen:pipeline\nvar pipeline = Steps(Map(strconv.Itoa))",
+			expectedError: errUnknownStepType,
+			errorMessage:  "pipeline: unknown step type [Map:1]: use a function literal or explicit type arguments",
+		}, {
+			name:          "incompatible_steps",
+			pipeline:      "//stepsgen:pipeline\nvar pipeline = Steps(Take[int](1), Skip[string](1))",
+			expectedError: errIncompatibleInArgType,
+			errorMessage:  "pipeline: incompatible input argument type [Skip:2]: [int!=string]",
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			src := "package sample\n\nimport . \"github.com/domahidizoltan/go-steps\"\n\n" + sc.pipeline + "\n"
+
+			_, err := generate("sample.go", []byte(src))
+
+			assert.ErrorIs(t, err, sc.expectedError)
+			if len(sc.errorMessage) > 0 {
+				assert.EqualError(t, err, sc.errorMessage)
+			}
+		})
+	}
+}
+
+func TestOutputName(t *testing.T) {
+	assert.Equal(t, "pipelines_stepsgen.go", outputName("pipelines.go"))
+	assert.Equal(t, "pipelines_stepsgen_test.go", outputName("pipelines_test.go"))
+}
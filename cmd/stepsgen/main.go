@@ -0,0 +1,74 @@
+// Stepsgen generates specialized, fully typed loops from the pipelines annotated with //stepsgen:pipeline.
+// The generated loops are not using reflection or boxing the items into interfaces,
+// so they could be used where the performance of the transformer is not enough.
+//
+// The pipelines are declared with Steps(...) (optionally followed by .Aggregate(...)),
+// as a package variable or as the single return statement of a function:
+//
+//	//go:generate go run github.com/domahidizoltan/go-steps/cmd/stepsgen
+//
+//	//stepsgen:pipeline
+//	func evenSquares() steps.StepsBranch {
+//		return steps.Steps(
+//			steps.Filter(func(i int) (bool, error) {
+//				return i%2 == 0, nil
+//			}),
+//			steps.Map(func(i int) (string, error) {
+//				return strconv.Itoa(i * i), nil
+//			}),
+//		)
+//	}
+//
+// The example above generates the evenSquaresGenerated(in []int) ([]string, error) function.
+// The parameters of an annotated function are added before the input of the generated function.
+// The generated function collects the outputs like AsSlice does, but it stops and returns at the first error.
+//
+// The supported steps are Map, Filter, Do, Take, Skip, TakeWhile and SkipWhile,
+// and the supported aggregators are Sum, Fold and Reduce.
+// The step types are taken from the explicit type arguments, or from the function literal passed to the step.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	file := flag.String("file", os.Getenv("GOFILE"), "the Go source file with the annotated pipelines")
+	output := flag.String("output", "", "the generated file (default <file>_stepsgen.go)")
+	flag.Parse()
+
+	if err := run(*file, *output); err != nil {
+		fmt.Fprintln(os.Stderr, "stepsgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(file, output string) error {
+	if len(file) == 0 {
+		return fmt.Errorf("missing source file (use -file or run it with go generate)")
+	}
+	if len(output) == 0 {
+		output = outputName(file)
+	}
+
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	generated, err := generate(file, src)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return os.WriteFile(output, generated, 0o644)
+}
+
+// outputName adds the _stepsgen suffix to the file name, keeping the test files as test files
+func outputName(file string) string {
+	if base, ok := strings.CutSuffix(file, "_test.go"); ok {
+		return base + "_stepsgen_test.go"
+	}
+	return strings.TrimSuffix(file, ".go") + "_stepsgen.go"
+}
@@ -0,0 +1,193 @@
+package steps
+
+import (
+	"context"
+	"reflect"
+	"runtime/debug"
+	"sync"
+)
+
+type (
+	// stepKernel is the typed function of a stateless single argument step (like Map or Filter).
+	// The kernels of the consecutive steps are composed into a single step function,
+	// so the intermediate values are not boxed into StepInput and StepOutput.
+	// The composed kernels are func(context.Context, any) (OUT0, bool, error) where the bool result is false for skipped items.
+	stepKernel interface {
+		start() any
+		compose(prev any, offset int) (any, bool)
+		stepFn(kernel any) StepFn
+	}
+
+	// kernelFn is the [stepKernel] of a step function. The kernels are created with the steps,
+	// so the composed functions are allocated only when the steps are fused.
+	kernelFn[IN0, OUT0 any] func(ctx context.Context, in IN0) (OUT0, bool, error)
+
+	// stepFusion is the fused step function of span consecutive steps
+	stepFusion struct {
+		stepFn StepFn
+		span   int
+	}
+
+	// stepFusions builds the fused steps of a validated branch once, when the branch is added to a transformer first (by With).
+	// The copies of the branch are sharing the fused steps, and they are never built when the fusion is disabled.
+	stepFusions struct {
+		once    sync.Once
+		fusions []stepFusion
+	}
+
+	// fusedStepError holds the error of a fused step with it's position in the fused steps, and it's input
+	fusedStepError struct {
+		offset int
+		args   Args
+		err    error
+	}
+)
+
+// withKernel adds the kernel to the step built from it.
+// The StepFn of the step is recorded, so the step is not fused when it's StepFn is overridden later.
+func withKernel(stepWrapper StepWrapper, kernel stepKernel) StepWrapper {
+	stepWrapper.kernel = kernel
+	stepWrapper.kernelStep = reflect.ValueOf(stepWrapper.StepFn).Pointer()
+	return stepWrapper
+}
+
+// fusable returns whether the step has a kernel, and it's StepFn is still the one built with the kernel
+func fusable(stepWrapper StepWrapper) bool {
+	return stepWrapper.kernel != nil && reflect.ValueOf(stepWrapper.StepFn).Pointer() == stepWrapper.kernelStep
+}
+
+func newStepKernel[IN0, OUT0 any](fn func(ctx context.Context, in IN0) (OUT0, bool, error)) stepKernel {
+	return kernelFn[IN0, OUT0](fn)
+}
+
+func (fn kernelFn[IN0, OUT0]) start() any {
+	return func(ctx context.Context, in any) (OUT0, bool, error) {
+		return callKernel(fn, ctx, in.(IN0), 0)
+	}
+}
+
+func (fn kernelFn[IN0, OUT0]) compose(prev any, offset int) (any, bool) {
+	prevKernel, ok := prev.(func(context.Context, any) (IN0, bool, error))
+	if !ok {
+		return nil, false
+	}
+	return func(ctx context.Context, in any) (OUT0, bool, error) {
+		mid, keep, err := prevKernel(ctx, in)
+		if err != nil || !keep {
+			var zero OUT0
+			return zero, false, err
+		}
+		return callKernel(fn, ctx, mid, offset)
+	}, true
+}
+
+func (fn kernelFn[IN0, OUT0]) stepFn(kernel any) StepFn {
+	k := kernel.(func(context.Context, any) (OUT0, bool, error))
+	return func(in StepInput) StepOutput {
+		out, keep, err := k(ctxOf(in), in.Args[0])
+		if err != nil {
+			return StepOutput{Error: err}
+		}
+		return StepOutput{
+			Args:    Args{out},
+			ArgsLen: 1,
+			Skip:    !keep,
+		}
+	}
+}
+
+// callKernel calls the kernel function of the fused step at offset, and converts it's error and panic to a [fusedStepError]
+func callKernel[IN0, OUT0 any](fn func(ctx context.Context, in IN0) (OUT0, bool, error), ctx context.Context, in IN0, offset int) (out OUT0, keep bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &fusedStepError{offset: offset, args: Args{in}, err: &StepPanicError{Value: r, Stack: debug.Stack()}}
+			keep = false
+		}
+	}()
+	out, keep, err = fn(ctx, in)
+	if err != nil {
+		return out, false, &fusedStepError{offset: offset, args: Args{in}, err: err}
+	}
+	return out, keep, nil
+}
+
+// fuseSteps fuses the consecutive steps having kernels (the steps with an overridden StepFn are not fused).
+// The fused step functions are returned by the position of their first step, the positions without fusion are empty.
+func fuseSteps(stepWrappers []StepWrapper) []stepFusion {
+	var fusions []stepFusion
+	for start := 0; start < len(stepWrappers); {
+		if !fusable(stepWrappers[start]) {
+			start++
+			continue
+		}
+
+		kernel, end := stepWrappers[start].kernel.start(), start+1
+		for ; end < len(stepWrappers) && fusable(stepWrappers[end]); end++ {
+			next, ok := stepWrappers[end].kernel.compose(kernel, end-start)
+			if !ok {
+				break
+			}
+			kernel = next
+		}
+
+		if end-start > 1 {
+			if fusions == nil {
+				fusions = make([]stepFusion, len(stepWrappers))
+			}
+			fusions[start] = stepFusion{
+				stepFn: stepWrappers[end-1].kernel.stepFn(kernel),
+				span:   end - start,
+			}
+		}
+		start = end
+	}
+	return fusions
+}
+
+// get returns the fused step functions by the position of their first step (see [fuseSteps])
+func (f *stepFusions) get(stepWrappers []StepWrapper) []stepFusion {
+	if f == nil {
+		return nil
+	}
+	f.once.Do(func() {
+		f.fusions = fuseSteps(stepWrappers)
+	})
+	return f.fusions
+}
+
+// stepFn returns the step function at pos, and the position of the next step
+func (t *transformer) stepFn(pos int) (StepFn, int) {
+	if pos < len(t.fusions) && t.fusions[pos].stepFn != nil {
+		return t.fusions[pos].stepFn, pos + t.fusions[pos].span
+	}
+	return t.steps[pos], pos + 1
+}
+
+// unfuseError returns the position and the input of the fused step which failed.
+// The recovered panics of the fused steps are converted to a [StepPanicError] of the failed step.
+func (t *transformer) unfuseError(pos int, in StepInput, err error) (int, StepInput, error) {
+	fusedErr, ok := err.(*fusedStepError)
+	if !ok {
+		return pos, in, err
+	}
+
+	pos += fusedErr.offset
+	in = StepInput{
+		Args:               fusedErr.args,
+		ArgsLen:            1,
+		TransformerOptions: in.TransformerOptions,
+	}
+	err = fusedErr.err
+	if panicErr, ok := err.(*StepPanicError); ok {
+		err = newStepPanicError(t.stepName(pos), pos, panicErr)
+	}
+	return pos, in, err
+}
+
+func (e *fusedStepError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fusedStepError) Unwrap() error {
+	return e.err
+}
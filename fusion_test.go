@@ -0,0 +1,138 @@
+package steps
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuseSteps(t *testing.T) {
+	steps := Steps(
+		Map(func(in string) (int, error) {
+			return strconv.Atoi(in)
+		}),
+		Filter(func(in int) (bool, error) {
+			return in > 1, nil
+		}),
+		Take[int](3),
+		Do(func(in int) error {
+			return nil
+		}),
+		Map(func(in int) (string, error) {
+			return strconv.Itoa(in * 2), nil
+		}),
+		Skip[string](1),
+		Map(func(in string) (string, error) {
+			return in + "!", nil
+		}),
+	)
+	require.NoError(t, steps.Validate())
+
+	var spans []int
+	for _, fusion := range steps.fusions.get(steps.StepWrappers) {
+		spans = append(spans, fusion.span)
+	}
+	assert.Equal(t, []int{2, 0, 0, 2, 0, 0, 0}, spans)
+	assert.Len(t, steps.Steps, 7)
+
+	input := []string{"1", "2", "3", "4", "5", "6"}
+	fused := Transform[string](input).With(steps).AsSlice()
+	notFused := Transform[string](input, WithoutFusion()).With(steps).AsSlice()
+
+	assert.Equal(t, []any{"6!", "8!"}, fused)
+	assert.Equal(t, notFused, fused)
+}
+
+func TestFuseSteps_OverriddenStepFn(t *testing.T) {
+	for _, sc := range []struct {
+		name    string
+		options []func(*TransformerOptions)
+	}{
+		{name: "fused"},
+		{name: "not_fused", options: []func(*TransformerOptions){WithoutFusion()}},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			var calls int
+			double := Map(func(in int) (int, error) {
+				return in * 2, nil
+			})
+			mapFn := double.StepFn
+			double.StepFn = func(in StepInput) StepOutput {
+				calls++
+				return mapFn(in)
+			}
+			steps := Steps(double, Filter(func(in int) (bool, error) {
+				return in > 2, nil
+			}))
+
+			actual := Transform[int]([]int{1, 2}, sc.options...).With(steps).AsSlice()
+
+			assert.Equal(t, []any{4}, actual)
+			assert.Equal(t, 2, calls)
+			assert.Empty(t, steps.fusions.get(steps.StepWrappers))
+		})
+	}
+}
+
+func TestProcess_FusedStepFailures(t *testing.T) {
+	steps := func() StepsBranch {
+		return Steps(
+			Map(func(in int) (int, error) {
+				return in * 10, nil
+			}),
+			Filter(func(in int) (bool, error) {
+				if in == 20 {
+					return false, errors.New("filter error")
+				}
+				return true, nil
+			}),
+			Map(func(in int) (string, error) {
+				if in == 30 {
+					panic("unexpected input")
+				}
+				return strconv.Itoa(in), nil
+			}),
+		)
+	}
+
+	for _, sc := range []struct {
+		name    string
+		options []func(*TransformerOptions)
+	}{
+		{name: "fused"},
+		{name: "not_fused", options: []func(*TransformerOptions){WithoutFusion()}},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			var failedItems []FailedItem
+			options := append([]func(*TransformerOptions){
+				WithErrorPolicy(DeadLetter),
+				WithDeadLetter(func(item FailedItem) {
+					failedItems = append(failedItems, item)
+				}),
+			}, sc.options...)
+
+			actual := Transform[int]([]int{1, 2, 3, 4}, options...).
+				With(steps()).
+				AsSlice()
+
+			assert.Equal(t, []any{"10", "40"}, actual)
+			require.Len(t, failedItems, 2)
+
+			assert.EqualError(t, failedItems[0].Error, "filter error")
+			assert.Equal(t, "Filter", failedItems[0].Step)
+			assert.Equal(t, 2, failedItems[0].Position)
+			assert.Equal(t, Args{20}, failedItems[0].Args)
+
+			var panicErr *StepPanicError
+			require.ErrorAs(t, failedItems[1].Error, &panicErr)
+			assert.Equal(t, "Map", panicErr.Step)
+			assert.Equal(t, 3, panicErr.Position)
+			assert.Equal(t, "Map", failedItems[1].Step)
+			assert.Equal(t, 3, failedItems[1].Position)
+			assert.Equal(t, Args{30}, failedItems[1].Args)
+		})
+	}
+}
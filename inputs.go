@@ -1,148 +1,433 @@
 package steps
 
 import (
-	"bufio"
+	"bytes"
+	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/jszwec/csvutil"
 )
 
-// FromCsv translates a CSV into a slice input
+type (
+	// csvSource is the [InputSource] of [CsvSource]
+	csvSource[T any] struct {
+		reader         io.Reader
+		withoutHeaders bool
+		csvReader      *csv.Reader
+		decoder        *csvutil.Decoder
+	}
+
+	// decodeFailures applies the decode error policy to the bad items of an input
+	decodeFailures struct {
+		policy  DecodeErrorPolicy
+		badRows int
+	}
+
+	// lazyFile is the file opened on the first read by [File]
+	lazyFile struct {
+		path string
+		open sync.Once
+		file *os.File
+		err  error
+	}
+)
+
+// FromCsv translates a CSV into a slice input.
+// The reader is closed after it is read when it is an io.Closer.
+// The input fails on the first bad record, unless the decode error policy of the transformer is set.
+// The read errors (like a missing file) are passed to the error handler, and the bad records to the panic handler.
 func FromCsv[T any](reader io.Reader) func(TransformerOptions) []T {
 	return func(opts TransformerOptions) []T {
+		src := CsvSource[T](reader, false)
+		defer src.Close()
+		res, err := collectSource(opts, src)
+		if err != nil {
+			reportInputError(opts, sourceError(opts, src, err))
+			return nil
+		}
+		return res
+	}
+}
+
+// FromStreamingCsv translates a CSV into a channel input.
+// The reader is closed when it is an io.Closer, and it's input is exhausted or the context of the transformer is done.
+func FromStreamingCsv[T any](reader io.Reader, withoutHeaders bool) func(TransformerOptions) chan T {
+	return func(opts TransformerOptions) chan T {
+		return fromSource(opts, CsvSource[T](reader, withoutHeaders))
+	}
+}
+
+// FromJson translates a JSON into a slice input.
+// The reader is closed after it is read when it is an io.Closer.
+// The input fails on the first bad item, unless the decode error policy of the transformer is set
+// (the items with syntax errors are always failing the input).
+// The read errors (like a missing file) are passed to the error handler, and the bad items to the panic handler.
+func FromJson[T any](reader io.Reader) func(TransformerOptions) []T {
+	return func(opts TransformerOptions) []T {
+		defer closeReader(reader)()
 		data, err := io.ReadAll(reader)
 		if err != nil && err != io.EOF {
-			opts.PanicHandler(err)
+			reportInputError(opts, err)
 			return nil
 		}
-		var res []T
-		if err := csvutil.Unmarshal(data, &res); err != nil {
-			opts.PanicHandler(err)
+		res, err := unmarshalJsonArray[T](data, sourceName(reader), opts.DecodeErrorPolicy)
+		if err != nil {
+			reportInputError(opts, err)
 			return nil
 		}
 		return res
 	}
 }
 
-// FromStreamingCsv translates a CSV into a channel input
-func FromStreamingCsv[T any](reader io.Reader, withoutHeaders bool) func(TransformerOptions) chan T {
-	return func(opts TransformerOptions) chan T {
-		resCh := make(chan T, opts.ChanSize)
-
-		header := []string{}
-		if withoutHeaders {
-			var t T
-			var err error
-			header, err = csvutil.Header(t, "csv")
-			if err != nil {
-				close(resCh)
-				opts.PanicHandler(err)
-				return resCh
+// unmarshalJsonArray decodes the items of a JSON array one by one, so the bad items could be skipped by the decode error policy.
+// The data which is not a JSON array is unmarshaled at once.
+func unmarshalJsonArray[T any](data []byte, name string, policy DecodeErrorPolicy) ([]T, error) {
+	var res []T
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if token, err := dec.Token(); err != nil || token != json.Delim('[') {
+		if err := json.Unmarshal(data, &res); err != nil {
+			return nil, jsonDecodeError(name, data, 0, -1, true, err)
+		}
+		return res, nil
+	}
+
+	res = []T{}
+	failures := decodeFailures{policy: policy}
+	for dec.More() {
+		start := dec.InputOffset()
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				// the decoder can't continue after a syntax error
+				return nil, jsonDecodeError(name, data, start, -1, true, err)
 			}
+			decodeErr := jsonDecodeError(name, data, start, dec.InputOffset(), true, err)
+			if err := failures.handle(decodeErr, false, nil); err != nil {
+				return nil, err
+			}
+			continue
 		}
+		res = append(res, item)
+	}
 
-		dec, err := csvutil.NewDecoder(csv.NewReader(reader), header...)
-		if err != nil && err != io.EOF {
-			close(resCh)
-			opts.PanicHandler(err)
-			return resCh
+	if _, err := dec.Token(); err != nil || dec.More() {
+		// the array is not closed or it is followed by other values
+		if err := json.Unmarshal(data, new([]T)); err != nil {
+			return nil, jsonDecodeError(name, data, 0, -1, true, err)
 		}
+	}
+	return res, nil
+}
 
-		go func(dec *csvutil.Decoder, resCh chan T) {
-			defer close(resCh)
-			for {
-				select {
-				case <-opts.Ctx.Done():
-					opts.ErrorHandler(opts.Ctx.Err())
-					return
-				default:
-					var data T
-					if err := dec.Decode(&data); err != nil {
-						if err == io.EOF {
-							return
-						}
-						opts.PanicHandler(err)
-						continue
-					}
-					resCh <- data
-				}
-			}
-		}(dec, resCh)
+// FromStreamingJson translates a JSON into a channel input.
+// The format of the JSON is defined by the optional mode (every line holds a single item by default, see [JsonMode]).
+// The reader is closed when it is an io.Closer, and it's input is exhausted or the context of the transformer is done.
+func FromStreamingJson[T any](reader io.Reader, mode ...JsonMode) func(TransformerOptions) chan T {
+	return func(opts TransformerOptions) chan T {
+		return fromSource(opts, JsonSource[T](reader, mode...))
+	}
+}
 
+// fromSource opens the source and starts producing it's items to a channel input.
+// The source is closed and the error is passed to the error handler when the source can't be opened.
+func fromSource[T any](opts TransformerOptions, src InputSource[T]) chan T {
+	resCh := make(chan T, opts.ChanSize)
+	if err := src.Open(opts.Ctx); err != nil {
+		close(resCh)
+		src.Close()
+		reportInputError(opts, sourceError(opts, src, err))
 		return resCh
 	}
+
+	go produce(opts, src, resCh)
+	return resCh
 }
 
-// FromJson translates a JSON into a slice input
-func FromJson[T any](reader io.Reader) func(TransformerOptions) []T {
-	return func(opts TransformerOptions) []T {
-		data, err := io.ReadAll(reader)
-		if err != nil && err != io.EOF {
-			opts.PanicHandler(err)
-			return nil
+// produce sends the items of the source to the channel input until the source returns io.EOF or the context is done.
+// The bad items are handled by the decode error policy of the transformer (by default they are skipped and passed to the panic handler).
+// Other errors of the source (like a failed read) are passed to the error handler, and the producer stops.
+// The source is closed when the producer is finished, or when the context is done (to unblock the pending reads).
+func produce[T any](opts TransformerOptions, src InputSource[T], resCh chan T) {
+	if opts.Ctx == nil {
+		opts.Ctx = context.Background()
+	}
+	failures := decodeFailures{policy: opts.DecodeErrorPolicy}
+	closeFn := sync.OnceFunc(func() {
+		src.Close()
+	})
+	stopClosing := context.AfterFunc(opts.Ctx, closeFn)
+	defer func() {
+		stopClosing()
+		closeFn()
+		close(resCh)
+	}()
+
+	for {
+		if opts.Ctx.Err() != nil {
+			reportCanceled(opts)
+			return
 		}
-		var res []T
-		if err := json.Unmarshal(data, &res); err != nil {
-			opts.PanicHandler(err)
-			return nil
+
+		data, err := src.Next()
+		switch {
+		case err == io.EOF:
+			return
+		case err != nil && opts.Ctx.Err() != nil:
+			// the read failed because the source was closed
+			reportCanceled(opts)
+			return
+		case err != nil:
+			if err := failures.handle(err, true, opts.PanicHandler); err != nil {
+				reportInputError(opts, sourceError(opts, src, err))
+				return
+			}
+		default:
+			select {
+			case resCh <- data:
+			case <-opts.Ctx.Done():
+				reportCanceled(opts)
+				return
+			}
 		}
-		return res
 	}
 }
 
-// FromStreamingJson translates a JSON into a channel input
-func FromStreamingJson[T any](reader io.Reader) func(TransformerOptions) chan T {
-	return func(opts TransformerOptions) chan T {
-		resCh := make(chan T, opts.ChanSize)
-		scanner := bufio.NewScanner(reader)
-		scanner.Split(bufio.ScanLines)
-
-		go func(scanner *bufio.Scanner, resCh chan T) {
-			defer close(resCh)
-			for {
-				select {
-				case <-opts.Ctx.Done():
-					opts.ErrorHandler(opts.Ctx.Err())
-					return
-				default:
-					if !scanner.Scan() {
-						return
-					}
-					if err := scanner.Err(); err != nil {
-						opts.PanicHandler(err)
-						continue
-					}
-
-					line := scanner.Text()
-					switch strings.TrimSpace(line) {
-					case "", "[", "]":
-						continue
-					default:
-						var data T
-						if err := json.Unmarshal([]byte(line), &data); err != nil {
-							opts.PanicHandler(err)
-							continue
-						}
-						resCh <- data
-					}
-
-				}
+// collectSource reads all the items of the source.
+// The bad items are handled by the decode error policy of the transformer (by default the first bad item fails the input).
+func collectSource[T any](opts TransformerOptions, src InputSource[T]) ([]T, error) {
+	if err := src.Open(opts.Ctx); err != nil {
+		return nil, err
+	}
+
+	var res []T
+	failures := decodeFailures{policy: opts.DecodeErrorPolicy}
+	for {
+		data, err := src.Next()
+		switch {
+		case err == io.EOF:
+			return res, nil
+		case err != nil:
+			if err := failures.handle(err, false, nil); err != nil {
+				return nil, err
 			}
-		}(scanner, resCh)
+		default:
+			res = append(res, data)
+		}
+	}
+}
 
-		return resCh
+// handle returns the error failing the input, or nil when the bad item is skipped by the decode error policy.
+// The errors which are not a [DecodeError] are always failing the input.
+// In the default mode the bad items are passed to report and skipped when skipByDefault is set, otherwise they are failing the input.
+func (f *decodeFailures) handle(err error, skipByDefault bool, report func(error)) error {
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		return err
+	}
+
+	switch f.policy.Mode {
+	case DecodeFail:
+		return err
+	case DecodeSkip:
+		return nil
+	case DecodeCollect:
+		f.badRows++
+		if f.policy.OnError != nil {
+			f.policy.OnError(decodeErr)
+		}
+		if f.policy.MaxBadRows > 0 && f.badRows > f.policy.MaxBadRows {
+			return fmt.Errorf("%w [%d]: %w", ErrTooManyBadRows, f.policy.MaxBadRows, err)
+		}
+		return nil
+	default:
+		if !skipByDefault {
+			return err
+		}
+		report(err)
+		return nil
 	}
 }
 
-// File is a helper function to define the file input for CSV or JSON inputs
-func File(filePath string) io.Reader {
-	f, err := os.Open(filePath)
-	if err != nil {
-		panic(err)
+// reportInputError passes the error failing the input to the panic handler when it's caused by the bad items
+// (see [DecodeErrorPolicy]), otherwise (like a missing file or a failed read) to the error handler
+func reportInputError(opts TransformerOptions, err error) {
+	if errors.Is(err, ErrDecodeFailed) || errors.Is(err, ErrTooManyBadRows) {
+		opts.PanicHandler(err)
+		return
+	}
+	opts.ErrorHandler(err)
+}
+
+// reportCanceled passes the error of the canceled context to the error handler,
+// unless the input was stopped because the transformer finished it's processing
+func reportCanceled(opts TransformerOptions) {
+	if context.Cause(opts.Ctx) != ErrInputStopped {
+		opts.ErrorHandler(opts.Ctx.Err())
+	}
+}
+
+// closeReader returns a function closing the reader (only once) when it is an io.Closer
+func closeReader(reader io.Reader) func() {
+	return sync.OnceFunc(func() {
+		if closer, ok := reader.(io.Closer); ok {
+			closer.Close()
+		}
+	})
+}
+
+// CsvSource creates an [InputSource] reading the items from a CSV.
+// The header is read on Open, or it is created from the csv tags of T when the CSV is without headers.
+// The reader is closed on Close when it is an io.Closer.
+func CsvSource[T any](reader io.Reader, withoutHeaders bool) InputSource[T] {
+	return &csvSource[T]{
+		reader:         reader,
+		withoutHeaders: withoutHeaders,
+	}
+}
+
+func (s *csvSource[T]) Open(context.Context) error {
+	header := []string{}
+	if s.withoutHeaders {
+		var t T
+		var err error
+		header, err = csvutil.Header(t, "csv")
+		if err != nil {
+			return err
+		}
+	}
+
+	s.csvReader = csv.NewReader(s.reader)
+	dec, err := csvutil.NewDecoder(s.csvReader, header...)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	s.decoder = dec
+	return nil
+}
+
+func (s *csvSource[T]) Next() (T, error) {
+	var data T
+	if s.decoder == nil {
+		return data, io.EOF
+	}
+	if err := s.decoder.Decode(&data); err != nil {
+		var zero T
+		if err == io.EOF {
+			return zero, err
+		}
+		return zero, s.decodeError(err)
+	}
+	return data, nil
+}
+
+// decodeError converts the parsing and unmarshaling errors of the last record to a [DecodeError]
+func (s *csvSource[T]) decodeError(err error) error {
+	decodeErr := &DecodeError{
+		Source: sourceName(s.reader),
+		Err:    err,
+	}
+	record := s.decoder.Record()
+
+	var parseErr *csv.ParseError
+	var fieldErr *csvutil.DecodeError
+	switch {
+	case errors.As(err, &parseErr):
+		decodeErr.Line, decodeErr.Column = parseErr.Line, parseErr.Column
+	case errors.As(err, &fieldErr):
+		decodeErr.Line, decodeErr.Column = fieldErr.Line, fieldErr.Column
+	case errors.Is(err, csvutil.ErrFieldCount) && len(record) != 0:
+		decodeErr.Line, _ = s.csvReader.FieldPos(0)
+	default:
+		return err
 	}
-	return f
+
+	if len(record) != 0 {
+		raw := strings.Builder{}
+		w := csv.NewWriter(&raw)
+		w.Write(record)
+		w.Flush()
+		decodeErr.Raw = strings.TrimSuffix(raw.String(), "\n")
+	}
+	return decodeErr
+}
+
+func (s *csvSource[T]) Close() error {
+	return closeSource(s.reader)
+}
+
+func (s *csvSource[T]) Meta() SourceMeta {
+	meta := SourceMeta{Name: sourceName(s.reader)}
+	if s.csvReader != nil {
+		meta.Offset = s.csvReader.InputOffset()
+	}
+	return meta
+}
+
+func (e *DecodeError) Error() string {
+	if len(e.Source) == 0 {
+		return fmt.Sprintf("%s [%d:%d]: %v", ErrDecodeFailed, e.Line, e.Column, e.Err)
+	}
+	return fmt.Sprintf("%s [%s:%d:%d]: %v", ErrDecodeFailed, e.Source, e.Line, e.Column, e.Err)
+}
+
+// Unwrap returns ErrDecodeFailed and the decoding error
+func (e *DecodeError) Unwrap() []error {
+	return []error{ErrDecodeFailed, e.Err}
+}
+
+// closeSource closes the reader of a source when it is an io.Closer
+func closeSource(reader io.Reader) error {
+	if closer, ok := reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// sourceName returns the name of the reader (like the path of a file) when it has a Name method
+func sourceName(reader io.Reader) string {
+	if named, ok := reader.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	return ""
+}
+
+// File is a helper function to define the file input for CSV or JSON inputs.
+// The file is opened on the first read, and the error of opening the file is returned by the read.
+// The file is closed by the inputs when they are finished.
+func File(filePath string) io.ReadCloser {
+	return &lazyFile{path: filePath}
+}
+
+func (f *lazyFile) Read(p []byte) (int, error) {
+	f.open.Do(func() {
+		f.file, f.err = os.Open(f.path)
+	})
+	if f.err != nil {
+		return 0, f.err
+	}
+	return f.file.Read(p)
+}
+
+// Close closes the opened file, and it prevents opening the file when it was not read yet
+func (f *lazyFile) Close() error {
+	f.open.Do(func() {
+		f.err = os.ErrClosed
+	})
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Close()
+}
+
+// Name returns the path of the file
+func (f *lazyFile) Name() string {
+	return f.path
 }
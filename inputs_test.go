@@ -6,11 +6,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type testPerson struct {
@@ -30,10 +37,11 @@ func (f failReader) Read([]byte) (int, error) {
 
 func TestFromCsv(t *testing.T) {
 	for _, sc := range []struct {
-		name        string
-		input       io.Reader
-		expected    []testPerson
-		expectedErr string
+		name            string
+		input           io.Reader
+		expected        []testPerson
+		expectedErr     string
+		expectedReadErr string
 	}{
 		{
 			name: "parse_csv",
@@ -48,9 +56,9 @@ func TestFromCsv(t *testing.T) {
 				{Name: "Doe", Code: 33},
 			},
 		}, {
-			name:        "reader_error",
-			input:       &failReader{errors.New("reader error")},
-			expectedErr: "reader error",
+			name:            "reader_error",
+			input:           &failReader{errors.New("reader error")},
+			expectedReadErr: "reader error",
 		}, {
 			name: "parse_error",
 			input: strings.NewReader(`
@@ -64,8 +72,13 @@ func TestFromCsv(t *testing.T) {
 		t.Run(sc.name, func(t *testing.T) {
 			opts := TransformerOptions{
 				PanicHandler: func(err error) {
+					assert.NotEmpty(t, sc.expectedErr)
 					assert.ErrorContains(t, err, sc.expectedErr)
 				},
+				ErrorHandler: func(err error) {
+					assert.NotEmpty(t, sc.expectedReadErr)
+					assert.ErrorContains(t, err, sc.expectedReadErr)
+				},
 			}
 			actual := FromCsv[testPerson](sc.input)(opts)
 			assert.Equal(t, sc.expected, actual)
@@ -75,12 +88,13 @@ func TestFromCsv(t *testing.T) {
 
 func TestStreamingCsv(t *testing.T) {
 	for _, sc := range []struct {
-		name           string
-		input          io.Reader
-		withoutHeaders bool
-		expected       []testPerson
-		expectedErr    string
-		cancelled      bool
+		name            string
+		input           io.Reader
+		withoutHeaders  bool
+		expected        []testPerson
+		expectedErr     string
+		expectedReadErr string
+		cancelled       bool
 	}{
 		{
 			name: "parse_csv",
@@ -106,10 +120,10 @@ Doe,,33`), 16),
 				{Name: "Doe", Code: 33},
 			},
 		}, {
-			name:        "reader_error",
-			input:       failReader{errors.New("reader error")},
-			expected:    []testPerson{},
-			expectedErr: "reader error",
+			name:            "reader_error",
+			input:           failReader{errors.New("reader error")},
+			expected:        []testPerson{},
+			expectedReadErr: "reader error",
 		}, {
 			name: "parse_error",
 			input: strings.NewReader(`
@@ -141,9 +155,14 @@ Doe,,33`), 16),
 			opts := TransformerOptions{
 				Ctx: ctx,
 				PanicHandler: func(err error) {
+					assert.NotEmpty(t, sc.expectedErr)
 					assert.ErrorContains(t, err, sc.expectedErr)
 				},
 				ErrorHandler: func(err error) {
+					if sc.expectedReadErr != "" {
+						assert.ErrorContains(t, err, sc.expectedReadErr)
+						return
+					}
 					assert.ErrorContains(t, err, "context canceled")
 				},
 			}
@@ -155,6 +174,13 @@ Doe,,33`), 16),
 				}
 				actual = append(actual, res)
 			}
+			if sc.cancelled {
+				// the producer could drop the item it read when it notices the cancellation while it's sending
+				require.NotEmpty(t, actual)
+				require.LessOrEqual(t, len(actual), len(sc.expected))
+				assert.Equal(t, sc.expected[:len(actual)], actual)
+				return
+			}
 			assert.Equal(t, sc.expected, actual)
 		})
 	}
@@ -162,10 +188,11 @@ Doe,,33`), 16),
 
 func TestFromJson(t *testing.T) {
 	for _, sc := range []struct {
-		name        string
-		input       io.Reader
-		expected    []testPerson
-		expectedErr string
+		name            string
+		input           io.Reader
+		expected        []testPerson
+		expectedErr     string
+		expectedReadErr string
 	}{
 		{
 			name: "parse_json",
@@ -181,9 +208,9 @@ func TestFromJson(t *testing.T) {
 				{Name: "Doe", Code: 33},
 			},
 		}, {
-			name:        "reader_error",
-			input:       &failReader{errors.New("reader error")},
-			expectedErr: "reader error",
+			name:            "reader_error",
+			input:           &failReader{errors.New("reader error")},
+			expectedReadErr: "reader error",
 		}, {
 			name:        "parse_error",
 			input:       strings.NewReader(`[{"xxxx"}]`),
@@ -193,8 +220,13 @@ func TestFromJson(t *testing.T) {
 		t.Run(sc.name, func(t *testing.T) {
 			opts := TransformerOptions{
 				PanicHandler: func(err error) {
+					assert.NotEmpty(t, sc.expectedErr)
 					assert.ErrorContains(t, err, sc.expectedErr)
 				},
+				ErrorHandler: func(err error) {
+					assert.NotEmpty(t, sc.expectedReadErr)
+					assert.ErrorContains(t, err, sc.expectedReadErr)
+				},
 			}
 			actual := FromJson[testPerson](sc.input)(opts)
 			assert.Equal(t, sc.expected, actual)
@@ -204,11 +236,12 @@ func TestFromJson(t *testing.T) {
 
 func TestStreamingJson(t *testing.T) {
 	for _, sc := range []struct {
-		name        string
-		input       io.Reader
-		expected    []testPerson
-		expectedErr string
-		cancelled   bool
+		name            string
+		input           io.Reader
+		expected        []testPerson
+		expectedErr     string
+		expectedReadErr string
+		cancelled       bool
 	}{
 		{
 			name: "parse_json",
@@ -234,6 +267,11 @@ func TestStreamingJson(t *testing.T) {
 				{Name: "Jane Doe", Dob: mustParseTime("1992-05-23T08:01:00Z"), Code: 22},
 				{Name: "Doe", Code: 33},
 			},
+		}, {
+			name:            "reader_error",
+			input:           failReader{errors.New("reader error")},
+			expected:        []testPerson{},
+			expectedReadErr: "reader error",
 		}, {
 			name: "parse_error",
 			input: strings.NewReader(`
@@ -263,9 +301,14 @@ func TestStreamingJson(t *testing.T) {
 			opts := TransformerOptions{
 				Ctx: ctx,
 				PanicHandler: func(err error) {
+					assert.NotEmpty(t, sc.expectedErr)
 					assert.ErrorContains(t, err, sc.expectedErr)
 				},
 				ErrorHandler: func(err error) {
+					if sc.expectedReadErr != "" {
+						assert.ErrorContains(t, err, sc.expectedReadErr)
+						return
+					}
 					assert.ErrorContains(t, err, "context canceled")
 				},
 			}
@@ -277,11 +320,420 @@ func TestStreamingJson(t *testing.T) {
 				}
 				actual = append(actual, res)
 			}
+			if sc.cancelled {
+				// the producer could drop the item it read when it notices the cancellation while it's sending
+				require.NotEmpty(t, actual)
+				require.LessOrEqual(t, len(actual), len(sc.expected))
+				assert.Equal(t, sc.expected[:len(actual)], actual)
+				return
+			}
 			assert.Equal(t, sc.expected, actual)
 		})
 	}
 }
 
+func TestStreamingJson_ArrayWithItemPerLine(t *testing.T) {
+	type item struct {
+		A int `json:"a"`
+	}
+	var errs []error
+	opts := TransformerOptions{
+		Ctx: context.Background(),
+		PanicHandler: func(err error) {
+			errs = append(errs, err)
+		},
+	}
+
+	actual := slices.Collect(chanValues(FromStreamingJson[item](strings.NewReader("[\n{\"a\":1},\n{\"a\":2}\n]"))(opts)))
+
+	assert.Equal(t, []item{{A: 1}, {A: 2}}, actual)
+	assert.Empty(t, errs)
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed atomic.Bool
+	done   chan struct{} // closed on the first Close when it is set
+}
+
+func (c *closeRecorder) Close() error {
+	if c.closed.CompareAndSwap(false, true) && c.done != nil {
+		close(c.done)
+	}
+	return nil
+}
+
+func TestStreamingInputs_Stopped(t *testing.T) {
+	csvInput := func() *closeRecorder {
+		data := strings.Builder{}
+		data.WriteString("name,code\n")
+		for i := range 1000 {
+			fmt.Fprintf(&data, "name%d,%d\n", i, i)
+		}
+		return &closeRecorder{Reader: strings.NewReader(data.String()), done: make(chan struct{})}
+	}
+	jsonInput := func() *closeRecorder {
+		data := strings.Builder{}
+		for i := range 1000 {
+			fmt.Fprintf(&data, "{\"name\":\"name%d\",\"code\":%d}\n", i, i)
+		}
+		return &closeRecorder{Reader: strings.NewReader(data.String()), done: make(chan struct{})}
+	}
+	failOnSecond := Map(func(in testPerson) (testPerson, error) {
+		if in.Code == 1 {
+			return in, errors.New("step error")
+		}
+		return in, nil
+	})
+
+	for _, sc := range []struct {
+		name string
+		run  func(input io.Reader, inputFn func(io.Reader) func(TransformerOptions) chan testPerson)
+	}{
+		{
+			name: "consumer_stopped_early",
+			run: func(input io.Reader, inputFn func(io.Reader) func(TransformerOptions) chan testPerson) {
+				for range TransformFn[testPerson](inputFn(input), WithChanSize(1)).WithSteps().AsRange() {
+					break
+				}
+			},
+		}, {
+			name: "step_failed",
+			run: func(input io.Reader, inputFn func(io.Reader) func(TransformerOptions) chan testPerson) {
+				TransformFn[testPerson](inputFn(input), WithChanSize(1), WithErrorHandler(expectsError(t, true))).
+					WithSteps(failOnSecond).
+					AsSlice()
+			},
+		}, {
+			name: "validation_failed",
+			run: func(input io.Reader, inputFn func(io.Reader) func(TransformerOptions) chan testPerson) {
+				TransformFn[testPerson](inputFn(input), WithChanSize(1), WithErrorHandler(expectsError(t, true))).
+					WithSteps(mapFn).
+					AsSlice()
+			},
+		}, {
+			name: "piped_consumer_stopped_early",
+			run: func(input io.Reader, inputFn func(io.Reader) func(TransformerOptions) chan testPerson) {
+				piped := TransformFn[testPerson](inputFn(input), WithChanSize(1)).WithSteps().Then(Steps())
+				for range piped.AsIndexedRange() {
+					break
+				}
+			},
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			goroutines := runtime.NumGoroutine()
+
+			var inputs []*closeRecorder
+			for range 10 {
+				csv, json := csvInput(), jsonInput()
+				inputs = append(inputs, csv, json)
+				sc.run(csv, func(r io.Reader) func(TransformerOptions) chan testPerson {
+					return FromStreamingCsv[testPerson](r, false)
+				})
+				sc.run(json, func(r io.Reader) func(TransformerOptions) chan testPerson {
+					return FromStreamingJson[testPerson](r)
+				})
+			}
+
+			// the producers are closing their input when they are stopped
+			timeout := time.After(time.Second)
+			for idx, input := range inputs {
+				select {
+				case <-input.done:
+				case <-timeout:
+					assert.Failf(t, "producer not stopped", "input %d is not closed", idx)
+					return
+				}
+			}
+
+			// the producers are closing their input before they are returning, so the goroutines are polled until they exit
+			// (polling in the test goroutine, because Eventually runs the condition in a new goroutine)
+			for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > goroutines && time.Now().Before(deadline); {
+				time.Sleep(10 * time.Millisecond)
+			}
+			assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+		})
+	}
+}
+
+func TestStreamingInputs_ClosedWhenExhausted(t *testing.T) {
+	input := &closeRecorder{Reader: strings.NewReader("name,code\nJohn,1\n")}
+
+	res := TransformFn[testPerson](FromStreamingCsv[testPerson](input, false), WithContext(nil)).
+		WithSteps().
+		AsSlice()
+
+	assert.Equal(t, []any{testPerson{Name: "John", Code: 1}}, res)
+	assert.Eventually(t, input.closed.Load, time.Second, time.Millisecond)
+}
+
+func TestDecodeErrorPolicy(t *testing.T) {
+	csvInput := "name,code\nJohn,1\nJane,x\nxxxx\nDoe,3\n"
+	jsonInput := "{\"name\":\"John\",\"code\":1}\n{\"name\":\"Jane\",\"code\":\"x\"}\n{\"xxxx\"}\n{\"name\":\"Doe\",\"code\":3}\n"
+	jsonArrayInput := "[\n  {\"name\":\"John\",\"code\":1},\n  {\"name\":\"Jane\",\"code\":\"x\"},\n  {\"name\":\"Doe\",\"code\":3},  {\"code\":true}\n]"
+
+	csvErrors := []DecodeError{
+		{Line: 3, Column: 6, Raw: "Jane,x"},
+		{Line: 4, Column: 1, Raw: "xxxx"},
+	}
+	jsonErrors := []DecodeError{
+		{Line: 2, Column: 1, Raw: "{\"name\":\"Jane\",\"code\":\"x\"}"},
+		{Line: 3, Column: 8, Raw: "{\"xxxx\"}"},
+	}
+	jsonArrayErrors := []DecodeError{
+		{Line: 3, Column: 3, Raw: "{\"name\":\"Jane\",\"code\":\"x\"}"},
+		{Line: 4, Column: 29, Raw: "{\"code\":true}"},
+	}
+	valid := []testPerson{{Name: "John", Code: 1}, {Name: "Doe", Code: 3}}
+
+	inputs := []struct {
+		name     string
+		read     func(TransformerOptions) []testPerson
+		badItems []DecodeError
+	}{
+		{
+			name: "csv",
+			read: func(opts TransformerOptions) []testPerson {
+				return FromCsv[testPerson](strings.NewReader(csvInput))(opts)
+			},
+			badItems: csvErrors,
+		}, {
+			name: "streaming_csv",
+			read: func(opts TransformerOptions) []testPerson {
+				return slices.Collect(chanValues(FromStreamingCsv[testPerson](strings.NewReader(csvInput), false)(opts)))
+			},
+			badItems: csvErrors,
+		}, {
+			name: "json",
+			read: func(opts TransformerOptions) []testPerson {
+				return FromJson[testPerson](strings.NewReader(jsonArrayInput))(opts)
+			},
+			badItems: jsonArrayErrors,
+		}, {
+			name: "streaming_json",
+			read: func(opts TransformerOptions) []testPerson {
+				return slices.Collect(chanValues(FromStreamingJson[testPerson](strings.NewReader(jsonInput))(opts)))
+			},
+			badItems: jsonErrors,
+		},
+	}
+
+	for _, input := range inputs {
+		t.Run(input.name, func(t *testing.T) {
+			streaming := strings.HasPrefix(input.name, "streaming")
+			defaultPanicCalls := 1
+			if streaming {
+				defaultPanicCalls = len(input.badItems)
+			}
+			for _, sc := range []struct {
+				name       string
+				policy     DecodeErrorPolicy
+				skipped    bool
+				failed     bool
+				onError    bool
+				panicCalls int
+			}{
+				{name: "default", skipped: streaming, failed: !streaming, panicCalls: defaultPanicCalls},
+				{name: "fail", policy: DecodeErrorPolicy{Mode: DecodeFail}, failed: true, panicCalls: 1},
+				{name: "skip", policy: DecodeErrorPolicy{Mode: DecodeSkip}, skipped: true},
+				{name: "collect", policy: DecodeErrorPolicy{Mode: DecodeCollect}, skipped: true, onError: true},
+				{name: "collect_too_many", policy: DecodeErrorPolicy{Mode: DecodeCollect, MaxBadRows: len(input.badItems) - 1}, failed: true, onError: true, panicCalls: 1},
+			} {
+				t.Run(sc.name, func(t *testing.T) {
+					var panicErrs []error
+					var collected []DecodeError
+					if sc.onError {
+						sc.policy.OnError = func(err *DecodeError) {
+							collected = append(collected, *err)
+						}
+					}
+					opts := TransformerOptions{
+						Ctx:               context.Background(),
+						DecodeErrorPolicy: sc.policy,
+						PanicHandler: func(err error) {
+							panicErrs = append(panicErrs, err)
+						},
+					}
+
+					actual := input.read(opts)
+
+					if sc.skipped {
+						assert.Equal(t, valid, actual)
+					}
+					if sc.failed && !streaming {
+						assert.Nil(t, actual)
+					}
+					if sc.failed && streaming {
+						assert.Equal(t, valid[:1], actual)
+					}
+					require.Len(t, panicErrs, sc.panicCalls)
+					for _, err := range panicErrs {
+						assert.ErrorIs(t, err, ErrDecodeFailed)
+					}
+					if sc.policy.MaxBadRows > 0 {
+						assert.ErrorIs(t, panicErrs[0], ErrTooManyBadRows)
+					}
+					if sc.name == "default" || sc.name == "fail" {
+						for idx, err := range panicErrs {
+							var decodeErr *DecodeError
+							require.ErrorAs(t, err, &decodeErr)
+							assert.Equal(t, input.badItems[idx], DecodeError{Line: decodeErr.Line, Column: decodeErr.Column, Raw: decodeErr.Raw})
+						}
+					}
+					if sc.onError {
+						assert.Len(t, collected, len(input.badItems))
+						for idx, err := range collected {
+							assert.Equal(t, input.badItems[idx], DecodeError{Line: err.Line, Column: err.Column, Raw: err.Raw})
+						}
+					}
+				})
+			}
+		})
+	}
+}
+
+func chanValues[T any](ch chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range ch {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.csv")
+	require.NoError(t, os.WriteFile(path, []byte("name,code\nJohn,x\n"), 0o600))
+
+	res := FromCsv[testPerson](File(path))(TransformerOptions{
+		PanicHandler: func(err error) {
+			var decodeErr *DecodeError
+			require.ErrorAs(t, err, &decodeErr)
+			assert.Equal(t, path, decodeErr.Source)
+			assert.EqualError(t, err, "decode failed ["+path+":2:6]: "+decodeErr.Err.Error())
+		},
+	})
+
+	assert.Nil(t, res)
+}
+
+func TestSources(t *testing.T) {
+	for _, sc := range []struct {
+		name     string
+		source   InputSource[testPerson]
+		expected []testPerson
+		offsets  []int64
+	}{
+		{
+			name:     "csv",
+			source:   CsvSource[testPerson](strings.NewReader("name,code\nJohn,1\n\"Jane\",22\n"), false),
+			expected: []testPerson{{Name: "John", Code: 1}, {Name: "Jane", Code: 22}},
+			offsets:  []int64{17, 27},
+		}, {
+			name:     "csv_without_headers",
+			source:   CsvSource[testPerson](strings.NewReader("John,,1\nJane,,22"), true),
+			expected: []testPerson{{Name: "John", Code: 1}, {Name: "Jane", Code: 22}},
+			offsets:  []int64{8, 16},
+		}, {
+			name:     "json",
+			source:   JsonSource[testPerson](strings.NewReader("[\n{\"name\":\"John\",\"code\":1}\r\n\n{\"name\":\"Jane\",\"code\":22}\n]")),
+			expected: []testPerson{{Name: "John", Code: 1}, {Name: "Jane", Code: 22}},
+			offsets:  []int64{28, 55},
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			require.NoError(t, sc.source.Open(context.Background()))
+
+			var actual []testPerson
+			var offsets []int64
+			for {
+				res, err := sc.source.Next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				actual = append(actual, res)
+				offsets = append(offsets, sc.source.Meta().Offset)
+			}
+
+			assert.Equal(t, sc.expected, actual)
+			assert.Equal(t, sc.offsets, offsets)
+			assert.NoError(t, sc.source.Close())
+		})
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.csv")
+	require.NoError(t, os.WriteFile(path, []byte("name,code\nJohn,1\n"), 0o600))
+
+	t.Run("opened_on_read", func(t *testing.T) {
+		file := File(path)
+		src := CsvSource[testPerson](file, false)
+		require.NoError(t, src.Open(context.Background()))
+		res, err := src.Next()
+
+		require.NoError(t, err)
+		assert.Equal(t, testPerson{Name: "John", Code: 1}, res)
+		assert.Equal(t, SourceMeta{Name: path, Offset: 17}, src.Meta())
+		assert.NoError(t, file.Close())
+		_, err = file.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, os.ErrClosed)
+	})
+
+	t.Run("not_existing_file", func(t *testing.T) {
+		file := File(filepath.Join(t.TempDir(), "missing.csv"))
+
+		_, err := file.Read(make([]byte, 1))
+
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		assert.NoError(t, file.Close())
+	})
+
+	t.Run("not_existing_file_of_inputs", func(t *testing.T) {
+		missing := filepath.Join(t.TempDir(), "missing.csv")
+		for name, run := range map[string]func(errorHandler func(error)) []any{
+			"FromCsv": func(errorHandler func(error)) []any {
+				return TransformFn[testPerson](FromCsv[testPerson](File(missing)), WithErrorHandler(errorHandler)).WithSteps().AsSlice()
+			},
+			"FromStreamingCsv": func(errorHandler func(error)) []any {
+				return TransformFn[testPerson](FromStreamingCsv[testPerson](File(missing), false), WithErrorHandler(errorHandler)).WithSteps().AsSlice()
+			},
+			"FromJson": func(errorHandler func(error)) []any {
+				return TransformFn[testPerson](FromJson[testPerson](File(missing)), WithErrorHandler(errorHandler)).WithSteps().AsSlice()
+			},
+			"FromStreamingJson": func(errorHandler func(error)) []any {
+				return TransformFn[testPerson](FromStreamingJson[testPerson](File(missing)), WithErrorHandler(errorHandler)).WithSteps().AsSlice()
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				var errs []error
+				var res []any
+				require.NotPanics(t, func() {
+					res = run(func(err error) {
+						errs = append(errs, err)
+					})
+				})
+
+				assert.Empty(t, res)
+				require.Len(t, errs, 1)
+				assert.ErrorIs(t, errs[0], os.ErrNotExist)
+			})
+		}
+	})
+
+	t.Run("closed_before_read", func(t *testing.T) {
+		file := File(path)
+
+		assert.NoError(t, file.Close())
+		_, err := file.Read(make([]byte, 1))
+
+		assert.ErrorIs(t, err, os.ErrClosed)
+	})
+}
+
 func mustParseTime(s string) *time.Time {
 	t, err := time.Parse(time.RFC3339, s)
 	if err != nil {
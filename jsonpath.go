@@ -0,0 +1,266 @@
+package steps
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type (
+	// jsonPathSource is the [InputSource] of [JsonPathSource]
+	jsonPathSource[T any] struct {
+		reader   io.Reader
+		path     string
+		segments []jsonPathSegment
+		tracker  *jsonTracker
+		decoder  *json.Decoder
+		frames   []jsonPathFrame
+		started  bool
+		done     bool
+	}
+
+	// jsonPathSegment selects the members of an object by name, the elements of an array by index, or all of them (wildcard)
+	jsonPathSegment struct {
+		name     string
+		index    int // the index of the selected array element, or -1 when an object member is selected
+		wildcard bool
+	}
+
+	// jsonPathFrame is an opened object or array, where the children are matched against the segment
+	jsonPathFrame struct {
+		segment int
+		object  bool
+		index   int
+	}
+)
+
+// FromStreamingJsonPath translates the values selected by the JSON path into a channel input.
+// The JSON is read token by token, so only the selected values are decoded, and the rest of the document is skipped.
+// See [JsonPathSource] for the supported JSON path syntax.
+// The reader is closed when it is an io.Closer, and it's input is exhausted or the context of the transformer is done.
+func FromStreamingJsonPath[T any](reader io.Reader, path string) func(TransformerOptions) chan T {
+	return func(opts TransformerOptions) chan T {
+		return fromSource(opts, JsonPathSource[T](reader, path))
+	}
+}
+
+// JsonPathSource creates an [InputSource] reading the values selected by the JSON path (like "$.data.items[*]").
+// The path starts with the root ($), followed by the member names (.name or ['name']), array indexes ([0])
+// and wildcards (.* or [*]) selecting all the members of an object or the elements of an array.
+// The decoding stops after a syntax error, and Open returns [ErrInvalidJsonPath] when the path can't be parsed.
+// The reader is closed on Close when it is an io.Closer.
+func JsonPathSource[T any](reader io.Reader, path string) InputSource[T] {
+	return &jsonPathSource[T]{
+		reader: reader,
+		path:   path,
+	}
+}
+
+func (s *jsonPathSource[T]) Open(context.Context) error {
+	segments, err := parseJsonPath(s.path)
+	if err != nil {
+		return err
+	}
+	s.segments = segments
+	s.tracker = &jsonTracker{reader: bufio.NewReader(s.reader), line: 1}
+	s.decoder = json.NewDecoder(s.tracker)
+	return nil
+}
+
+func (s *jsonPathSource[T]) Next() (T, error) {
+	var zero T
+	for !s.done {
+		s.tracker.consume(s.decoder.InputOffset())
+		if len(s.frames) == 0 {
+			if s.started || !s.decoder.More() {
+				s.done = true
+				break
+			}
+			s.started = true
+			if data, ok, err := s.enter(0); ok || err != nil {
+				return data, err
+			}
+			continue
+		}
+
+		frame := &s.frames[len(s.frames)-1]
+		if !s.decoder.More() {
+			// the closing delimiter of the object or array
+			if _, err := s.decoder.Token(); err != nil {
+				return zero, s.fail(err)
+			}
+			s.frames = s.frames[:len(s.frames)-1]
+			continue
+		}
+
+		var key string
+		if frame.object {
+			token, err := s.decoder.Token()
+			if err != nil {
+				return zero, s.fail(err)
+			}
+			key, _ = token.(string)
+		}
+		index := frame.index
+		frame.index++
+
+		if !s.segments[frame.segment].matches(key, index, frame.object) {
+			if err := s.skip(); err != nil {
+				return zero, s.fail(err)
+			}
+			continue
+		}
+		if data, ok, err := s.enter(frame.segment + 1); ok || err != nil {
+			return data, err
+		}
+	}
+	return zero, io.EOF
+}
+
+// enter decodes the next value when all the segments are matched, otherwise it opens the object or array matched by the segment.
+// The scalar values are skipped when the path is not matched.
+func (s *jsonPathSource[T]) enter(segment int) (T, bool, error) {
+	var data T
+	if segment == len(s.segments) {
+		start := s.decoder.InputOffset()
+		err := s.decoder.Decode(&data)
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case err == nil:
+			return data, true, nil
+		case errors.As(err, &typeErr):
+			// the decoder skips the rest of the value with a type error, so it could continue with the next value
+			var zero T
+			return zero, false, s.tracker.decodeError(sourceName(s.reader), start, s.decoder.InputOffset(), err)
+		default:
+			var zero T
+			s.done = true
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) || err == io.ErrUnexpectedEOF {
+				return zero, false, s.tracker.decodeError(sourceName(s.reader), start, -1, err)
+			}
+			return zero, false, err
+		}
+	}
+
+	token, err := s.decoder.Token()
+	if err != nil {
+		return data, false, s.fail(err)
+	}
+	if delim, ok := token.(json.Delim); ok && (delim == '{' || delim == '[') {
+		s.frames = append(s.frames, jsonPathFrame{segment: segment, object: delim == '{'})
+	}
+	return data, false, nil
+}
+
+// skip skips the next value token by token, so the skipped values are not kept in memory
+func (s *jsonPathSource[T]) skip() error {
+	depth := 0
+	for {
+		token, err := s.decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+		s.tracker.consume(s.decoder.InputOffset())
+	}
+}
+
+// fail stops the decoding, and converts the syntax errors of the tokens to a [DecodeError]
+func (s *jsonPathSource[T]) fail(err error) error {
+	s.done = true
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || err == io.ErrUnexpectedEOF {
+		return s.tracker.tokenError(sourceName(s.reader), s.decoder.InputOffset(), err)
+	}
+	return err
+}
+
+func (s *jsonPathSource[T]) Close() error {
+	return closeSource(s.reader)
+}
+
+func (s *jsonPathSource[T]) Meta() SourceMeta {
+	meta := SourceMeta{Name: sourceName(s.reader)}
+	if s.decoder != nil {
+		meta.Offset = s.decoder.InputOffset()
+	}
+	return meta
+}
+
+func (s jsonPathSegment) matches(key string, index int, object bool) bool {
+	switch {
+	case s.wildcard:
+		return true
+	case object:
+		return s.index < 0 && s.name == key
+	default:
+		return s.index == index
+	}
+}
+
+// parseJsonPath parses the segments of the JSON path following the root ($)
+func parseJsonPath(path string) ([]jsonPathSegment, error) {
+	invalid := func(pos int) error {
+		return fmt.Errorf("%w [%s:%d]", ErrInvalidJsonPath, path, pos+1)
+	}
+	if !strings.HasPrefix(path, "$") {
+		return nil, invalid(0)
+	}
+
+	var segments []jsonPathSegment
+	for pos := 1; pos < len(path); {
+		switch path[pos] {
+		case '.':
+			end := pos + 1
+			for end < len(path) && path[end] != '.' && path[end] != '[' {
+				end++
+			}
+			name := path[pos+1 : end]
+			if len(name) == 0 || strings.ContainsAny(name, "]'\"") {
+				return nil, invalid(pos)
+			}
+			segments = append(segments, jsonPathSegment{name: name, index: -1, wildcard: name == "*"})
+			pos = end
+		case '[':
+			end := strings.IndexByte(path[pos:], ']')
+			if end < 0 {
+				return nil, invalid(pos)
+			}
+			end += pos
+			inner := path[pos+1 : end]
+			switch {
+			case inner == "*":
+				segments = append(segments, jsonPathSegment{index: -1, wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				segments = append(segments, jsonPathSegment{name: inner[1 : len(inner)-1], index: -1})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, invalid(pos)
+				}
+				segments = append(segments, jsonPathSegment{index: index})
+			}
+			pos = end + 1
+		default:
+			return nil, invalid(pos)
+		}
+	}
+	return segments, nil
+}
@@ -0,0 +1,245 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJsonPathSource(t *testing.T) {
+	export := `{
+		"meta": {"items": [{"name": "Meta", "code": 0}], "tags": ["a", {"b": [1, 2]}]},
+		"data": {
+			"count": 3,
+			"items": [
+				{"name": "John", "code": 1},
+				{"name": "Jane", "code": 2},
+				{"name": "Doe", "code": 3}
+			]
+		}
+	}`
+	pages := `{"pages": [
+		{"items": [{"name": "John", "code": 1}]},
+		{"items": []},
+		{"other": [{"name": "Other"}]},
+		{"items": [{"name": "Jane", "code": 2}, {"name": "Doe", "code": 3}]}
+	]}`
+	all := []testPerson{{Name: "John", Code: 1}, {Name: "Jane", Code: 2}, {Name: "Doe", Code: 3}}
+
+	for _, sc := range []struct {
+		name     string
+		input    string
+		path     string
+		expected []testPerson
+	}{
+		{name: "array_elements", input: export, path: "$.data.items[*]", expected: all},
+		{name: "quoted_names", input: export, path: `$['data']["items"].*`, expected: all},
+		{name: "array_index", input: export, path: "$.data.items[1]", expected: all[1:2]},
+		{name: "nested_wildcards", input: pages, path: "$.pages[*].items[*]", expected: all},
+		{name: "root_elements", input: `[{"name": "John", "code": 1}, {"name": "Jane", "code": 2}]`, path: "$[*]", expected: all[:2]},
+		{name: "root", input: `{"name": "John", "code": 1}`, path: "$", expected: all[:1]},
+		{name: "object_members", input: `{"a": {"name": "John", "code": 1}, "b": {"name": "Jane", "code": 2}}`, path: "$.*", expected: all[:2]},
+		{name: "not_found", input: export, path: "$.data.missing[*]"},
+		{name: "not_an_array", input: export, path: "$.data.count[*]"},
+		{name: "empty_input", input: "", path: "$.data.items[*]"},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			src := JsonPathSource[testPerson](strings.NewReader(sc.input), sc.path)
+			require.NoError(t, src.Open(context.Background()))
+
+			var actual []testPerson
+			for {
+				res, err := src.Next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				actual = append(actual, res)
+			}
+
+			assert.Equal(t, sc.expected, actual)
+		})
+	}
+}
+
+func TestJsonPathSource_Failures(t *testing.T) {
+	t.Run("invalid_path", func(t *testing.T) {
+		for _, path := range []string{"", "data.items", "$.", "$..items", "$.items[", "$.items[-1]", "$.items[x]", "$.items]"} {
+			err := JsonPathSource[testPerson](strings.NewReader("{}"), path).Open(context.Background())
+			assert.ErrorIs(t, err, ErrInvalidJsonPath, path)
+		}
+		err := JsonPathSource[testPerson](strings.NewReader("{}"), "$.items[x]").Open(context.Background())
+		assert.EqualError(t, err, "invalid JSON path [$.items[x]:8]")
+	})
+
+	for _, sc := range []struct {
+		name     string
+		input    string
+		expected []testPerson
+		badItems []DecodeError
+	}{
+		{
+			name:     "type_error",
+			input:    "{\"items\": [\n  {\"name\": \"John\", \"code\": 1},\n  {\"name\": \"Jane\", \"code\": \"x\"},\n  {\"name\": \"Doe\", \"code\": 3}\n]}",
+			expected: []testPerson{{Name: "John", Code: 1}, {Name: "Doe", Code: 3}},
+			badItems: []DecodeError{{Line: 3, Column: 3, Raw: "{\"name\": \"Jane\", \"code\": \"x\"}"}},
+		}, {
+			name:     "syntax_error",
+			input:    "{\"items\": [\n  {\"name\": \"John\", \"code\": 1},\n  {\"name\": \"Jane\" \"code\": 2},\n  {\"name\": \"Doe\", \"code\": 3}\n]}",
+			expected: []testPerson{{Name: "John", Code: 1}},
+			badItems: []DecodeError{{Line: 3, Column: 19, Raw: "{\"name\": \"Jane\" \"code\": 2},"}},
+		}, {
+			name:     "syntax_error_in_skipped_value",
+			input:    "{\"meta\": {\"a\" 1},\n \"items\": [{\"name\": \"John\", \"code\": 1}]}",
+			badItems: []DecodeError{{Line: 1, Column: 15, Raw: "1},"}}, // the skipped part of the line is not buffered
+		}, {
+			name:     "truncated",
+			input:    "{\"items\": [\n  {\"name\": \"John\", \"code\": 1},\n",
+			expected: []testPerson{{Name: "John", Code: 1}},
+			badItems: []DecodeError{{Line: 3, Column: 1}},
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			src := JsonPathSource[testPerson](strings.NewReader(sc.input), "$.items[*]")
+			require.NoError(t, src.Open(context.Background()))
+
+			var actual []testPerson
+			var badItems []DecodeError
+			for {
+				res, err := src.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					var decodeErr *DecodeError
+					require.ErrorAs(t, err, &decodeErr)
+					badItems = append(badItems, DecodeError{Line: decodeErr.Line, Column: decodeErr.Column, Raw: decodeErr.Raw})
+					continue
+				}
+				actual = append(actual, res)
+			}
+
+			assert.Equal(t, sc.expected, actual)
+			assert.Equal(t, sc.badItems, badItems)
+		})
+	}
+}
+
+func TestJsonPathSource_ConstantMemory(t *testing.T) {
+	data := strings.Builder{}
+	data.WriteString(`{"meta": {"skipped": [`)
+	for i := range 10000 {
+		fmt.Fprintf(&data, "{\"name\":\"name%d\",\"values\":[%d,%d]},\n", i, i, i)
+	}
+	data.WriteString(`{}]}, "data": {"items": [`)
+	for i := range 10000 {
+		fmt.Fprintf(&data, "{\"name\":\"name%d\",\"code\":%d},\n", i, i)
+	}
+	data.WriteString(`{"name":"last"}]}}`)
+
+	src := JsonPathSource[testPerson](strings.NewReader(data.String()), "$.data.items[*]")
+	require.NoError(t, src.Open(context.Background()))
+
+	count, maxBuffered := 0, 0
+	for {
+		_, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		count++
+		maxBuffered = max(maxBuffered, len(src.(*jsonPathSource[testPerson]).tracker.buf))
+	}
+
+	assert.Equal(t, 10001, count)
+	assert.Less(t, maxBuffered, 8*1024)
+	assert.Equal(t, int64(data.Len()), src.Meta().Offset)
+}
+
+func TestFromStreamingJsonPath(t *testing.T) {
+	input := `{"data": {"items": [{"name": "John", "code": 1}, {"name": "Jane", "code": 2}, {"name": "Doe", "code": 3}]}}`
+
+	t.Run("chan_size", func(t *testing.T) {
+		ch := FromStreamingJsonPath[testPerson](strings.NewReader(input), "$.data.items[*]")(TransformerOptions{
+			Ctx:      context.Background(),
+			ChanSize: 2,
+		})
+
+		assert.Equal(t, 2, cap(ch))
+		assert.Len(t, slices.Collect(chanValues(ch)), 3)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var errs []error
+		ch := FromStreamingJsonPath[testPerson](strings.NewReader(input), "$.data.items[*]")(TransformerOptions{
+			Ctx: ctx,
+			ErrorHandler: func(err error) {
+				errs = append(errs, err)
+			},
+		})
+
+		var actual []testPerson
+		for res := range ch {
+			cancel()
+			actual = append(actual, res)
+		}
+
+		// the producer could read one more item before it notices the cancellation, but it never reaches the last one
+		expected := []testPerson{{Name: "John", Code: 1}, {Name: "Jane", Code: 2}}
+		require.NotEmpty(t, actual)
+		require.LessOrEqual(t, len(actual), len(expected))
+		assert.Equal(t, expected[:len(actual)], actual)
+		require.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], context.Canceled)
+	})
+
+	t.Run("invalid_path", func(t *testing.T) {
+		input := &closeRecorder{Reader: strings.NewReader(input)}
+		var openErr error
+		ch := FromStreamingJsonPath[testPerson](input, "data.items")(TransformerOptions{
+			Ctx: context.Background(),
+			ErrorHandler: func(err error) {
+				openErr = err
+			},
+		})
+
+		assert.Empty(t, slices.Collect(chanValues(ch)))
+		assert.ErrorIs(t, openErr, ErrInvalidJsonPath)
+		assert.True(t, input.closed.Load())
+	})
+}
+
+func ExampleFromStreamingJsonPath() {
+	type person struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	reader := strings.NewReader(`{
+		"meta": {"count": 2},
+		"data": {
+			"items": [
+				{"id": 1, "name": "John Doe"},
+				{"id": 2, "name": "Jane Doe"}
+			]
+		}
+	}`)
+
+	res := TransformFn[person](FromStreamingJsonPath[person](reader, "$.data.items[*]")).
+		WithSteps(
+			Map(func(in person) (string, error) {
+				return in.Name, nil
+			}),
+		).
+		AsSlice()
+
+	fmt.Println(res)
+	// Output: [John Doe Jane Doe]
+}
@@ -0,0 +1,300 @@
+package steps
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+type (
+	// jsonSource is the [InputSource] of [JsonSource]
+	jsonSource[T any] struct {
+		reader  io.Reader
+		mode    JsonMode
+		lines   *bufio.Reader
+		tracker *jsonTracker
+		decoder *json.Decoder
+		array   bool
+		offset  int64
+		line    int
+		done    bool
+	}
+
+	// jsonTracker keeps the bytes read by the JSON decoder after the last decoded item,
+	// so the position and the raw content of a bad item is known without keeping the whole input.
+	jsonTracker struct {
+		reader    io.Reader
+		buf       []byte
+		offset    int64 // input offset of the first byte of buf
+		line      int   // line of the first byte of buf (starting from 1)
+		lineStart int64 // input offset of the line start
+	}
+)
+
+var errJsonArrayExpected = errors.New("JSON array expected")
+
+// JsonSource creates an [InputSource] reading the items from a JSON.
+// The format of the JSON is defined by the optional mode (every line holds a single item by default, see [JsonMode]).
+// The items are decoded one by one, so the memory usage doesn't depend on the size of the input.
+// The decoding stops after a syntax error, except in JsonLines mode, where the next line is decoded.
+// The reader is closed on Close when it is an io.Closer.
+func JsonSource[T any](reader io.Reader, mode ...JsonMode) InputSource[T] {
+	src := &jsonSource[T]{reader: reader}
+	if len(mode) != 0 {
+		src.mode = mode[0]
+	}
+	return src
+}
+
+func (s *jsonSource[T]) Open(context.Context) error {
+	buffered := bufio.NewReader(s.reader)
+	if s.mode == JsonLines {
+		s.lines = buffered
+		return nil
+	}
+
+	mode := s.mode
+	if mode == JsonAuto {
+		mode = JsonConcatenated
+		if c, err := firstNonSpace(buffered); err == nil && c == '[' {
+			mode = JsonArray
+		}
+	}
+
+	s.tracker = &jsonTracker{reader: buffered, line: 1}
+	s.decoder = json.NewDecoder(s.tracker)
+	s.array = mode == JsonArray
+	if !s.array {
+		return nil
+	}
+
+	token, err := s.decoder.Token()
+	var syntaxErr *json.SyntaxError
+	switch {
+	case err == io.EOF:
+		s.done = true
+		return nil
+	case errors.As(err, &syntaxErr):
+		return s.decodeError(0, err)
+	case err != nil:
+		return err
+	case token != json.Delim('['):
+		return s.decodeError(0, errJsonArrayExpected)
+	}
+	s.tracker.consume(s.decoder.InputOffset())
+	return nil
+}
+
+func (s *jsonSource[T]) Next() (T, error) {
+	if s.mode == JsonLines {
+		return s.nextLine()
+	}
+	return s.nextValue()
+}
+
+// nextLine decodes the next line holding an item
+func (s *jsonSource[T]) nextLine() (T, error) {
+	var data T
+	for !s.done {
+		line, err := s.lines.ReadBytes('\n')
+		s.offset += int64(len(line))
+		if err != nil {
+			s.done = true
+			if err != io.EOF {
+				return data, err
+			}
+		}
+
+		s.line++
+		switch string(bytes.TrimSpace(line)) {
+		case "", "[", "]":
+			continue
+		}
+		// the items of a JSON array written one per line are separated by commas
+		line = bytes.TrimSuffix(bytes.TrimRight(line, " \t\r\n"), []byte(","))
+		if err := json.Unmarshal(line, &data); err != nil {
+			var zero T
+			decodeErr := jsonDecodeError(sourceName(s.reader), line, 0, int64(len(line)), true, err)
+			decodeErr.Line += s.line - 1
+			return zero, decodeErr
+		}
+		return data, nil
+	}
+	return data, io.EOF
+}
+
+// nextValue decodes the next value of the concatenated values or the array elements
+func (s *jsonSource[T]) nextValue() (T, error) {
+	var data T
+	if s.done {
+		return data, io.EOF
+	}
+
+	if !s.decoder.More() {
+		// the closing bracket of the array, or the end of the input
+		s.done = true
+		_, err := s.decoder.Token()
+		var syntaxErr *json.SyntaxError
+		switch {
+		case err == io.EOF && s.array:
+			return data, s.tracker.tokenError(sourceName(s.reader), s.decoder.InputOffset(), io.ErrUnexpectedEOF)
+		case errors.As(err, &syntaxErr):
+			return data, s.tracker.tokenError(sourceName(s.reader), s.decoder.InputOffset(), err)
+		case err != nil && err != io.EOF:
+			return data, err
+		}
+		return data, io.EOF
+	}
+
+	start := s.decoder.InputOffset()
+	err := s.decoder.Decode(&data)
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == nil:
+		s.tracker.consume(s.decoder.InputOffset())
+		return data, nil
+	case errors.As(err, &typeErr):
+		// the decoder skips the rest of the value with a type error, so it could continue with the next value
+		var zero T
+		decodeErr := s.tracker.decodeError(sourceName(s.reader), start, s.decoder.InputOffset(), err)
+		s.tracker.consume(s.decoder.InputOffset())
+		return zero, decodeErr
+	case errors.As(err, &syntaxErr) || err == io.ErrUnexpectedEOF:
+		var zero T
+		s.done = true
+		return zero, s.decodeError(start, err)
+	default:
+		s.done = true
+		var zero T
+		return zero, err
+	}
+}
+
+func (s *jsonSource[T]) decodeError(start int64, err error) *DecodeError {
+	return s.tracker.decodeError(sourceName(s.reader), start, -1, err)
+}
+
+func (s *jsonSource[T]) Close() error {
+	return closeSource(s.reader)
+}
+
+func (s *jsonSource[T]) Meta() SourceMeta {
+	meta := SourceMeta{
+		Name:   sourceName(s.reader),
+		Offset: s.offset,
+	}
+	if s.decoder != nil {
+		meta.Offset = s.decoder.InputOffset()
+	}
+	return meta
+}
+
+// firstNonSpace returns the first byte of the input which is not a whitespace without consuming it
+func firstNonSpace(reader *bufio.Reader) (byte, error) {
+	for n := 1; ; n++ {
+		peeked, err := reader.Peek(n)
+		if len(peeked) < n {
+			return 0, err
+		}
+		switch c := peeked[n-1]; c {
+		case ' ', '\t', '\r', '\n':
+		default:
+			return c, nil
+		}
+	}
+}
+
+func (t *jsonTracker) Read(p []byte) (int, error) {
+	n, err := t.reader.Read(p)
+	t.buf = append(t.buf, p[:n]...)
+	return n, err
+}
+
+// consume drops the bytes before the offset, and counts their lines
+func (t *jsonTracker) consume(offset int64) {
+	consumed := t.buf[:offset-t.offset]
+	if lines := bytes.Count(consumed, []byte("\n")); lines != 0 {
+		t.line += lines
+		t.lineStart = t.offset + int64(bytes.LastIndexByte(consumed, '\n')) + 1
+	}
+	t.buf = t.buf[len(consumed):]
+	t.offset = offset
+}
+
+// decodeError converts the error of decoding the value between the start and end input offsets to a [DecodeError].
+// The rest of the line is read when the end of the value is unknown, because the decoder can't continue after the error.
+func (t *jsonTracker) decodeError(name string, start, end int64, err error) *DecodeError {
+	return t.newDecodeError(name, start, end, true, err)
+}
+
+// tokenError converts the error of reading the token at the input offset to a [DecodeError]
+func (t *jsonTracker) tokenError(name string, offset int64, err error) *DecodeError {
+	return t.newDecodeError(name, offset, -1, false, err)
+}
+
+func (t *jsonTracker) newDecodeError(name string, start, end int64, locate bool, err error) *DecodeError {
+	if end >= 0 {
+		end -= t.offset
+	} else {
+		t.readLine()
+	}
+
+	decodeErr := jsonDecodeError(name, t.buf, start-t.offset, end, locate, err)
+	if decodeErr.Line == 1 {
+		decodeErr.Column += int(t.offset - t.lineStart)
+	}
+	decodeErr.Line += t.line - 1
+	return decodeErr
+}
+
+// readLine reads the input until the end of the line (up to 4KB), so the line of the error could be reported
+func (t *jsonTracker) readLine() {
+	p := make([]byte, 512)
+	for read := 0; read < 4096 && !bytes.Contains(t.buf[len(t.buf)-read:], []byte("\n")); {
+		n, err := t.reader.Read(p)
+		t.buf = append(t.buf, p[:n]...)
+		read += n
+		if err != nil {
+			return
+		}
+	}
+}
+
+// jsonDecodeError converts the error of decoding the JSON item between the start and end offsets of the data to a [DecodeError].
+// The syntax error is located by validating the item when locate is set (the offsets of the decoder errors are not comparable),
+// and it's column is the column of the bad byte (the last byte read until the error, see the offset of [json.SyntaxError]),
+// otherwise the error is at the start of the item.
+// The raw content is the (buffered part of the) line of the error when the end of the item is unknown (it is negative).
+func jsonDecodeError(name string, data []byte, start, end int64, locate bool, err error) *DecodeError {
+	start += int64(len(data[start:]) - len(bytes.TrimLeft(data[start:], " \t\r\n,")))
+	pos := start
+
+	var syntaxErr *json.SyntaxError
+	if locate && errors.As(err, &syntaxErr) {
+		var raw json.RawMessage
+		if errors.As(json.Unmarshal(data[start:], &raw), &syntaxErr) {
+			pos = min(start+max(syntaxErr.Offset-1, 0), int64(len(data)))
+		}
+	}
+
+	before := data[:pos]
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	if end < 0 {
+		start, end = int64(lineStart), int64(len(data))
+		if lineEnd := bytes.IndexByte(data[pos:], '\n'); lineEnd >= 0 {
+			end = pos + int64(lineEnd)
+		}
+	}
+
+	return &DecodeError{
+		Source: name,
+		Line:   bytes.Count(before, []byte("\n")) + 1,
+		Column: len(before) - lineStart + 1,
+		Raw:    string(bytes.TrimSpace(data[start:end])),
+		Err:    err,
+	}
+}
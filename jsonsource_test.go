@@ -0,0 +1,233 @@
+package steps
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJsonSource_Modes(t *testing.T) {
+	prettyArray := `[
+		{
+			"name": "John",
+			"code": 1
+		},
+		{"name": "Jane",
+		 "code": 2}, {"name": "Doe", "code": 3}
+	]`
+	ndjson := "{\"name\":\"John\",\"code\":1}\n{\"name\":\"Jane\",\"code\":2}\r\n\n{\"name\":\"Doe\",\"code\":3}"
+	concatenated := `{"name":"John","code":1}{"name":"Jane","code":2} {"name":"Doe",
+		"code":3}`
+	longName := strings.Repeat("x", 100*1024)
+	expected := []testPerson{{Name: "John", Code: 1}, {Name: "Jane", Code: 2}, {Name: "Doe", Code: 3}}
+
+	for _, sc := range []struct {
+		name     string
+		input    string
+		mode     []JsonMode
+		expected []testPerson
+	}{
+		{name: "lines", input: ndjson, expected: expected},
+		{name: "lines_in_brackets", input: "[\n" + ndjson + "\n]", expected: expected},
+		{name: "lines_longer_than_64kb", input: `{"name":"` + longName + `"}`, expected: []testPerson{{Name: longName}}},
+		{name: "array", input: prettyArray, mode: []JsonMode{JsonArray}, expected: expected},
+		{name: "empty_array", input: " [ ]", mode: []JsonMode{JsonArray}},
+		{name: "array_longer_than_64kb", input: `[{"name":"` + longName + `"}]`, mode: []JsonMode{JsonArray}, expected: []testPerson{{Name: longName}}},
+		{name: "concatenated", input: concatenated, mode: []JsonMode{JsonConcatenated}, expected: expected},
+		{name: "concatenated_ndjson", input: ndjson, mode: []JsonMode{JsonConcatenated}, expected: expected},
+		{name: "auto_array", input: "\n  " + prettyArray, mode: []JsonMode{JsonAuto}, expected: expected},
+		{name: "auto_concatenated", input: concatenated, mode: []JsonMode{JsonAuto}, expected: expected},
+		{name: "auto_empty", input: "", mode: []JsonMode{JsonAuto}},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			src := JsonSource[testPerson](strings.NewReader(sc.input), sc.mode...)
+			require.NoError(t, src.Open(context.Background()))
+
+			var actual []testPerson
+			for {
+				res, err := src.Next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				actual = append(actual, res)
+			}
+
+			assert.Equal(t, sc.expected, actual)
+			assert.Equal(t, int64(len(sc.input)), src.Meta().Offset)
+		})
+	}
+}
+
+func TestJsonSource_DecodeErrors(t *testing.T) {
+	for _, sc := range []struct {
+		name     string
+		input    string
+		mode     JsonMode
+		expected []testPerson
+		badItems []DecodeError
+	}{
+		{
+			name:     "array_type_error",
+			input:    "[\n  {\"name\":\"John\",\"code\":1},\n  {\"name\":\"Jane\",\n   \"code\":\"x\"}, {\"name\":\"Doe\",\"code\":3}\n]",
+			mode:     JsonArray,
+			expected: []testPerson{{Name: "John", Code: 1}, {Name: "Doe", Code: 3}},
+			badItems: []DecodeError{{Line: 3, Column: 3, Raw: "{\"name\":\"Jane\",\n   \"code\":\"x\"}"}},
+		}, {
+			name:     "array_syntax_error",
+			input:    "[\n  {\"name\":\"John\",\"code\":1},\n  {\"name\" \"Jane\"},\n  {\"name\":\"Doe\",\"code\":3}\n]",
+			mode:     JsonArray,
+			expected: []testPerson{{Name: "John", Code: 1}},
+			badItems: []DecodeError{{Line: 3, Column: 11, Raw: "{\"name\" \"Jane\"},"}},
+		}, {
+			name:     "array_not_closed",
+			input:    "[{\"name\":\"John\",\"code\":1},\n",
+			mode:     JsonArray,
+			expected: []testPerson{{Name: "John", Code: 1}},
+			badItems: []DecodeError{{Line: 2, Column: 1}},
+		}, {
+			name:     "not_an_array",
+			input:    "\n {\"name\":\"John\",\"code\":1}",
+			mode:     JsonArray,
+			badItems: []DecodeError{{Line: 2, Column: 2, Raw: "{\"name\":\"John\",\"code\":1}"}},
+		}, {
+			name:     "concatenated_syntax_error",
+			input:    "{\"name\":\"John\",\"code\":1} {\"name\":}\n{\"name\":\"Doe\",\"code\":3}",
+			mode:     JsonConcatenated,
+			expected: []testPerson{{Name: "John", Code: 1}},
+			badItems: []DecodeError{{Line: 1, Column: 34, Raw: "{\"name\":}"}},
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			src := JsonSource[testPerson](strings.NewReader(sc.input), sc.mode)
+
+			var actual []testPerson
+			var badItems []DecodeError
+			collect := func(err error) {
+				var decodeErr *DecodeError
+				require.ErrorAs(t, err, &decodeErr)
+				badItems = append(badItems, DecodeError{Line: decodeErr.Line, Column: decodeErr.Column, Raw: decodeErr.Raw})
+			}
+
+			if err := src.Open(context.Background()); err != nil {
+				collect(err)
+			} else {
+				for {
+					res, err := src.Next()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						collect(err)
+						continue
+					}
+					actual = append(actual, res)
+				}
+			}
+
+			assert.Equal(t, sc.expected, actual)
+			assert.Equal(t, sc.badItems, badItems)
+		})
+	}
+}
+
+// TestJsonDecodeError_Column pins the column of the syntax errors, which are reported at the bad byte (like the columns of the CSV errors)
+func TestJsonDecodeError_Column(t *testing.T) {
+	for _, sc := range []struct {
+		name     string
+		data     string
+		start    int64
+		expected int
+	}{
+		{name: "missing_colon", data: `{"xxxx"}`, expected: 8},
+		{name: "missing_colon_after_key", data: `  {"name" "Jane"},`, expected: 11},
+		{name: "missing_value", data: `{"name":"John","code":1} {"name":}`, start: 25, expected: 34},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			var raw json.RawMessage
+			err := json.Unmarshal([]byte(sc.data[sc.start:]), &raw)
+			require.Error(t, err)
+
+			decodeErr := jsonDecodeError("", []byte(sc.data), sc.start, -1, true, err)
+
+			assert.Equal(t, 1, decodeErr.Line)
+			assert.Equal(t, sc.expected, decodeErr.Column)
+		})
+	}
+}
+
+func TestJsonSource_ReaderError(t *testing.T) {
+	src := JsonSource[testPerson](io.MultiReader(
+		strings.NewReader(`[{"name":"John","code":1},`),
+		failReader{errors.New("reader error")},
+	), JsonArray)
+	require.NoError(t, src.Open(context.Background()))
+
+	res, err := src.Next()
+	require.NoError(t, err)
+	assert.Equal(t, testPerson{Name: "John", Code: 1}, res)
+
+	_, err = src.Next()
+	assert.EqualError(t, err, "reader error")
+	assert.NotErrorIs(t, err, ErrDecodeFailed)
+	_, err = src.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestJsonSource_ConstantMemory(t *testing.T) {
+	data := strings.Builder{}
+	data.WriteString("[\n")
+	for i := range 10000 {
+		fmt.Fprintf(&data, "  {\"name\":\"name%d\",\"code\":%d},\n", i, i)
+	}
+	data.WriteString("  {\"name\":\"last\"}\n]")
+
+	src := JsonSource[testPerson](strings.NewReader(data.String()), JsonArray)
+	require.NoError(t, src.Open(context.Background()))
+
+	count, maxBuffered := 0, 0
+	for {
+		_, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		count++
+		maxBuffered = max(maxBuffered, len(src.(*jsonSource[testPerson]).tracker.buf))
+	}
+
+	assert.Equal(t, 10001, count)
+	assert.Less(t, maxBuffered, 8*1024)
+}
+
+func ExampleFromStreamingJson_array() {
+	type person struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	reader := strings.NewReader(`[
+		{"id": 1, "name": "John Doe"},
+		{
+			"id": 2,
+			"name": "Jane Doe"
+		}
+	]`)
+
+	res := TransformFn[person](FromStreamingJson[person](reader, JsonArray)).
+		WithSteps(
+			Map(func(in person) (string, error) {
+				return in.Name, nil
+			}),
+		).
+		AsSlice()
+
+	fmt.Println(res)
+	// Output: [John Doe Jane Doe]
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"reflect"
+	"time"
 )
 
 // WithName adds a name to the transformer
@@ -28,7 +30,7 @@ func WithErrorHandler(handler func(error)) func(*TransformerOptions) {
 	}
 }
 
-// WithPanicHandler sets the panic handler used by the transformer input
+// WithPanicHandler sets the panic handler used by the transformer inputs for the bad items (see [DecodeErrorPolicy])
 func WithPanicHandler(handler func(error)) func(*TransformerOptions) {
 	return func(opts *TransformerOptions) {
 		opts.PanicHandler = handler
@@ -49,6 +51,111 @@ func WithChanSize(size uint) func(*TransformerOptions) {
 	}
 }
 
+// WithDecodeErrorPolicy sets what happens when an input can't decode an item.
+// By default the streaming inputs are skipping the bad items (passing them to the panic handler), and other inputs fail.
+func WithDecodeErrorPolicy(policy DecodeErrorPolicy) func(*TransformerOptions) {
+	return func(opts *TransformerOptions) {
+		opts.DecodeErrorPolicy = policy
+	}
+}
+
+// WithErrorPolicy sets what happens when a step returns an error (the processing stops by default).
+// The policy could be overridden for a single step using [OnError].
+func WithErrorPolicy(policy ErrorPolicy) func(*TransformerOptions) {
+	return func(opts *TransformerOptions) {
+		opts.ErrorPolicy = policy
+	}
+}
+
+// WithDeadLetter sets the sink receiving the failed items when the [DeadLetter] error policy is used.
+// Without a sink the errors of the failed items are passed to the error handler.
+func WithDeadLetter(sink func(FailedItem)) func(*TransformerOptions) {
+	return withDeadLetter(func(_ context.Context, item FailedItem) error {
+		sink(item)
+		return nil
+	})
+}
+
+// WithDeadLetterChan sends the failed items to the given channel when the [DeadLetter] error policy is used.
+// Sending blocks the processing until the failed item is received, or the transformer context is done
+// (the processing stops with the error of the context).
+func WithDeadLetterChan(ch chan<- FailedItem) func(*TransformerOptions) {
+	return withDeadLetter(func(ctx context.Context, item FailedItem) error {
+		select {
+		case ch <- item:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// WithDeadLetterWriter writes the failed items as lines to the given writer when the [DeadLetter] error policy is used.
+func WithDeadLetterWriter(writer io.Writer) func(*TransformerOptions) {
+	return WithDeadLetter(func(item FailedItem) {
+		fmt.Fprintln(writer, item.String())
+	})
+}
+
+// withDeadLetter sets the dead-letter sink receiving the transformer context.
+// The processing stops with the error returned by the sink.
+func withDeadLetter(sink func(ctx context.Context, item FailedItem) error) func(*TransformerOptions) {
+	return func(opts *TransformerOptions) {
+		opts.DeadLetter = sink
+	}
+}
+
+// WithoutFusion disables fusing the consecutive stateless steps (like Map and Filter) into a single step.
+// The fused steps are behaving the same way, so it is mostly useful for debugging.
+func WithoutFusion() func(*TransformerOptions) {
+	return func(opts *TransformerOptions) {
+		opts.NoFusion = true
+	}
+}
+
+// EmitEvery emits the aggregated value after every N aggregated inputs
+func EmitEvery(count uint64) func(*AggregatorOptions) {
+	return func(opts *AggregatorOptions) {
+		opts.EveryItems = count
+	}
+}
+
+// EmitEveryDuration emits the aggregated value when the duration passed since the last emission.
+// The elapsed time is checked when an input is aggregated, and a channel input also emits the aggregated value on time
+// while it's waiting for the next item (other inputs like iter.Seq are blocking the transformer while they are waiting).
+func EmitEveryDuration(duration time.Duration) func(*AggregatorOptions) {
+	return func(opts *AggregatorOptions) {
+		opts.EveryDuration = duration
+	}
+}
+
+// EmitWhen emits the aggregated value when the trigger returns true for the aggregated input.
+// The input type of the trigger is validated against the input of the aggregator.
+func EmitWhen[IN0 any](trigger func(in IN0) bool) func(*AggregatorOptions) {
+	return func(opts *AggregatorOptions) {
+		opts.Trigger = func(in any) bool {
+			return trigger(in.(IN0))
+		}
+		opts.triggerType = reflect.TypeFor[IN0]()
+	}
+}
+
+// ResetOnEmit resets the aggregator state after each emission,
+// so the emitted values are aggregating only the inputs since the previous emission.
+func ResetOnEmit() func(*AggregatorOptions) {
+	return func(opts *AggregatorOptions) {
+		opts.ResetOnEmit = true
+	}
+}
+
+func buildAggregatorOpts(options ...func(*AggregatorOptions)) AggregatorOptions {
+	var opts AggregatorOptions
+	for _, withOption := range options {
+		withOption(&opts)
+	}
+	return opts
+}
+
 func buildOpts(options ...func(*TransformerOptions)) TransformerOptions {
 	opts := TransformerOptions{
 		Ctx:       context.Background(),
@@ -58,9 +165,13 @@ func buildOpts(options ...func(*TransformerOptions)) TransformerOptions {
 	for _, withOption := range options {
 		withOption(&opts)
 	}
+	if opts.Ctx == nil {
+		opts.Ctx = context.Background()
+	}
 	if opts.ErrorHandler == nil {
+		logWriter := opts.LogWriter
 		opts.ErrorHandler = func(err error) {
-			fmt.Fprintln(opts.LogWriter, "error occured:", err)
+			fmt.Fprintln(logWriter, "error occured:", err)
 		}
 	}
 	if opts.PanicHandler == nil {
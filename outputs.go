@@ -1,72 +1,53 @@
 package steps
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"iter"
 	"reflect"
+	"sync"
+	"time"
 
 	"github.com/jszwec/csvutil"
 )
 
 func handleErrWithTrName[T any, IT inputType[T]](t stepsTransformer[T, IT], err error, errorHandler func(error)) {
+	errorHandler(errWithTrName(t, err))
+}
+
+func errWithTrName[T any, IT inputType[T]](t stepsTransformer[T, IT], err error) error {
 	if len(t.options.Name) != 0 {
 		err = fmt.Errorf("[%s] %w", t.options.Name, err)
 	}
-	errorHandler(err)
+	return err
 }
 
 // AsRange returns the transformer output as a single value iterator ready to be used by the range keyword
 func (t stepsTransformer[T, IT]) AsRange() iter.Seq[any] {
 	return func(yield func(any) bool) {
+		defer t.finishRun(t.startRun())
 		if t.error != nil {
 			handleErrWithTrName(t, t.error, t.options.ErrorHandler)
 			return
 		}
 
-		t.resetStates()
-		var terminated bool
-		var err error
-		switch in := any(t.input).(type) {
-		case chan T:
-			idx := -1
-			var val any
-			for {
-				v, isOpen := <-in
-				if !isOpen && idx < 0 {
-					break
-				}
-				if idx >= 0 {
-					_, terminated, err = process(val, yield, &t.transformer, !isOpen)
-					if terminated || err != nil {
-						if err != nil {
-							handleErrWithTrName(t, err, t.options.ErrorHandler)
-						}
-						break
-					}
-				}
-				if !isOpen {
-					break
-				}
-				val = v
-				idx++
+		t.forEachInput(func(_ any, val T, isLastItem bool) bool {
+			_, terminated, err := process(val, yield, &t.transformer, isLastItem)
+			if err != nil {
+				handleErrWithTrName(t, err, t.options.ErrorHandler)
 			}
-		case []T:
-			lastIdx := len(in) - 1
-			for idx, v := range in {
-				_, terminated, err = process(v, yield, &t.transformer, idx == lastIdx)
-				if terminated || err != nil {
-					if err != nil {
-						handleErrWithTrName(t, err, t.options.ErrorHandler)
-					}
-					break
-				}
+			return !terminated && err == nil
+		}, func(_ any, isLastItem bool) bool {
+			terminated, err := processFlush(yield, &t.transformer, isLastItem)
+			if err != nil {
+				handleErrWithTrName(t, err, t.options.ErrorHandler)
 			}
-		default:
-			panic("unsupported input type")
-		}
+			return !terminated && err == nil
+		})
 	}
 }
 
@@ -75,55 +56,250 @@ func (t stepsTransformer[T, IT]) AsKeyValueRange() iter.Seq2[any, any] {
 	return t.AsIndexedRange()
 }
 
-// AsIndexedRange returns the transformer output as a key-value iterator ready to be used by the range keyword
+// AsIndexedRange returns the transformer output as a key-value iterator ready to be used by the range keyword.
+// The keys are the indexes of the inputs (or the keys of the iter.Seq2 input), unless the last step has multiple outputs.
 func (t stepsTransformer[T, IT]) AsIndexedRange() iter.Seq2[any, any] {
 	return func(yield func(any, any) bool) {
+		defer t.finishRun(t.startRun())
 		if t.error != nil {
 			handleErrWithTrName(t, t.error, t.options.ErrorHandler)
 			return
 		}
 
-		t.resetStates()
-		var terminated bool
-		var err error
-		switch in := any(t.input).(type) {
-		case chan T:
-			idx := -1
-			var val any
-			for {
-				v, isOpen := <-in
-				if !isOpen && idx < 0 {
-					break
+		t.forEachInput(func(key any, val T, isLastItem bool) bool {
+			_, terminated, err := processIndexed(key, val, yield, &t.transformer, isLastItem)
+			if err != nil {
+				handleErrWithTrName(t, err, t.options.ErrorHandler)
+			}
+			return !terminated && err == nil
+		}, func(key any, isLastItem bool) bool {
+			terminated, err := processFlushIndexed(key, yield, &t.transformer, isLastItem)
+			if err != nil {
+				handleErrWithTrName(t, err, t.options.ErrorHandler)
+			}
+			return !terminated && err == nil
+		})
+	}
+}
+
+// startRun prepares a new run of the transformer, and returns the parent context and the cancel function of the run for finishRun.
+// The steps are using the context of the run, which is canceled when the run is finished (also when the consumer stopped early).
+func (t *stepsTransformer[T, IT]) startRun() (context.Context, context.CancelCauseFunc) {
+	parentCtx := t.options.Ctx
+	if parentCtx == nil {
+		parentCtx = context.Background()
+	}
+	ctx, cancel := context.WithCancelCause(parentCtx)
+	t.options.Ctx = ctx
+	t.resetStates()
+	t.done = false
+	if t.runInput != nil {
+		t.input = t.runInput(t.options)
+	}
+	return parentCtx, cancel
+}
+
+// finishRun cancels the context of the run started by startRun.
+// The streaming inputs created by [TransformFn] are stopped when the run is finished, and their remaining items are drained.
+func (t *stepsTransformer[T, IT]) finishRun(parentCtx context.Context, cancel context.CancelCauseFunc) {
+	cancel(ErrInputStopped)
+	t.options.Ctx = parentCtx
+	if t.streaming {
+		drainInput[T](t.input)
+	}
+}
+
+// drainInput drains the remaining items of a channel input created by [TransformFn] (it's producer is stopped by the context of the run)
+func drainInput[T any, IT inputType[T]](input IT) {
+	if in, ok := any(input).(chan T); ok {
+		go func() {
+			for range in {
+			}
+		}()
+	}
+}
+
+// forEachInput calls fn with the input items and their keys until it returns false.
+// The keys of the slice, channel and iter.Seq inputs are the item indexes.
+// The last item of the streaming inputs is detected by reading the next item before processing the current one.
+// The slice and channel inputs are read directly, so fn (and the transformer it captures) is not moved to the heap.
+// The steps waiting to be flushed on time are flushed by calling flush with the key of the last processed item (see forEachChanInput).
+func (t *stepsTransformer[T, IT]) forEachInput(fn func(key any, val T, isLastItem bool) bool, flush func(key any, isLastItem bool) bool) {
+	switch in := any(t.input).(type) {
+	case []T:
+		lastIdx := len(in) - 1
+		for idx, v := range in {
+			if !fn(idx, v, idx == lastIdx) {
+				return
+			}
+		}
+	case chan T:
+		forEachChanInput(&t.transformer, in, fn, flush)
+	default:
+		t.forEachSeqInput(fn)
+	}
+}
+
+// forEachChanInput calls fn with the items of the channel input and their indexes until it returns false.
+// While the input is waiting for the next item, the steps are flushed when their flush time passed (see [StepWrapper.FlushAt])
+// or when their buffered outputs are ready (see [StepWrapper.Ready]).
+// When a step could be flushed on time, the item read ahead is processed (as not the last item) before waiting for the next one,
+// and the processing is finished by calling flush when the input is exhausted after it's last item was already processed.
+func forEachChanInput[T any](t *transformer, in chan T, fn func(key any, val T, isLastItem bool) bool, flush func(key any, isLastItem bool) bool) {
+	var (
+		val     T
+		pending bool // val is read ahead, but it's not processed yet
+		timer   *time.Timer
+	)
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	timed := t.hasTimedFlush()
+	for idx := 0; ; {
+		var (
+			next     T
+			isOpen   bool
+			received bool
+		)
+		if pending && timed {
+			select {
+			case next, isOpen = <-in:
+				received = true
+			default:
+				pending = false
+				if !fn(idx, val, false) {
+					return
 				}
-				if idx >= 0 {
-					_, terminated, err = processIndexed(idx, val, yield, &t.transformer, !isOpen)
-					if terminated || err != nil {
-						if err != nil {
-							handleErrWithTrName(t, err, t.options.ErrorHandler)
-						}
-						break
+				idx++
+			}
+		}
+
+		if !received {
+			at, ready := t.nextFlush(), t.readyChans()
+			if at.IsZero() && len(ready) == 0 {
+				next, isOpen = <-in
+			} else {
+				var timerCh <-chan time.Time
+				if !at.IsZero() {
+					if timer == nil {
+						timer = time.NewTimer(time.Until(at))
+					} else {
+						timer.Reset(time.Until(at))
 					}
+					timerCh = timer.C
 				}
-				if !isOpen {
-					break
+				var flushNow bool
+				if next, isOpen, flushNow = waitChanInput(in, timerCh, ready); flushNow {
+					if !flush(idx-1, false) {
+						return
+					}
+					continue
 				}
-				val = v
-				idx++
 			}
-		case []T:
-			lastIdx := len(in) - 1
-			for idx, v := range in {
-				_, terminated, err = processIndexed(idx, v, yield, &t.transformer, idx == lastIdx)
-				if terminated || err != nil {
-					if err != nil {
-						handleErrWithTrName(t, err, t.options.ErrorHandler)
-					}
-					break
+		}
+
+		switch {
+		case !isOpen && pending:
+			fn(idx, val, true)
+			return
+		case !isOpen:
+			if idx > 0 {
+				flush(idx-1, true)
+			}
+			return
+		case pending && !fn(idx, val, false):
+			return
+		case pending:
+			idx++
+		}
+		val, pending = next, true
+	}
+}
+
+// waitChanInput waits for the next item of the channel input, the timer, or any of the ready channels of the steps.
+// It returns whether the steps must be flushed instead of processing the next item.
+func waitChanInput[T any](in chan T, timer <-chan time.Time, ready []<-chan struct{}) (next T, isOpen bool, flush bool) {
+	var readyCh <-chan struct{}
+	switch len(ready) {
+	case 0:
+	case 1:
+		readyCh = ready[0]
+	default:
+		merged, stop := mergeReady(ready)
+		defer stop()
+		readyCh = merged
+	}
+
+	select {
+	case next, isOpen = <-in:
+		return next, isOpen, false
+	case <-timer:
+	case <-readyCh:
+	}
+	return next, false, true
+}
+
+// mergeReady returns a channel closed when any of the ready channels is closed, and a function stopping the wait.
+// The number of the ready channels is only known at runtime, so they are selected by reflection on a separate goroutine
+// (the channel input is not passed to reflect, so it's not moved to the heap).
+func mergeReady(ready []<-chan struct{}) (<-chan struct{}, func()) {
+	merged, stopped := make(chan struct{}), make(chan struct{})
+	cases := make([]reflect.SelectCase, 0, len(ready)+1)
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(stopped)})
+	for _, ch := range ready {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+	}
+	go func() {
+		if chosen, _, _ := reflect.Select(cases); chosen != 0 {
+			close(merged)
+		}
+	}()
+	return merged, func() {
+		close(stopped)
+	}
+}
+
+// forEachSeqInput is the variant of forEachInput for the iter.Seq, iter.Seq2 and piped inputs
+func (t *stepsTransformer[T, IT]) forEachSeqInput(fn func(key any, val T, isLastItem bool) bool) {
+	switch in := any(t.input).(type) {
+	case iter.Seq[T]:
+		withLookahead(func(yield func(any, T) bool) {
+			idx := 0
+			for v := range in {
+				if !yield(idx, v) {
+					return
 				}
+				idx++
 			}
-		default:
-			panic("unsupported input type")
+		}, fn)
+	case iter.Seq2[any, T]:
+		withLookahead(in, fn)
+	case pipedInput:
+		withLookahead(func(yield func(any, T) bool) {
+			in(func(key, out any) bool {
+				return yield(key, out.(T))
+			})
+		}, fn)
+	default:
+		panic("unsupported input type")
+	}
+}
+
+// withLookahead calls fn with the previous item of the sequence, so it knows when the item is the last one.
+// The sequence is pulled, so fn is not captured by the sequence and it does not escape.
+func withLookahead[T any](seq iter.Seq2[any, T], fn func(key any, val T, isLastItem bool) bool) {
+	next, stop := iter.Pull2(seq)
+	defer stop()
+	key, val, ok := next()
+	for ok {
+		nextKey, nextVal, hasNext := next()
+		if !fn(key, val, !hasNext) {
+			return
 		}
+		key, val, ok = nextKey, nextVal, hasNext
 	}
 }
 
@@ -231,3 +407,169 @@ func (t stepsTransformer[T, IT]) ToStreamingJson(writer io.Writer) {
 		}
 	}
 }
+
+// withErrorCollector returns a copy of the transformer collecting the errors instead of passing them to the error and panic handlers,
+// and a function returning the collected errors joined.
+// The inputs created for each run (like the sources, the piped transformers and the inputs of [TransformFn]) are also passing
+// their errors to the collector. The producers of the streaming inputs are reporting from their own goroutine, so the errors are locked.
+func (t stepsTransformer[T, IT]) withErrorCollector() (stepsTransformer[T, IT], func() error) {
+	var (
+		errs []error
+		mu   sync.Mutex
+	)
+	collect := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}
+	t.options.ErrorHandler = collect
+	t.options.PanicHandler = collect
+	return t, func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		return errors.Join(errs...)
+	}
+}
+
+// AsSliceE is a variant of AsSlice returning the errors (joined) instead of passing them to the error handler
+func (t stepsTransformer[T, IT]) AsSliceE() ([]any, error) {
+	t, errs := t.withErrorCollector()
+	res := t.AsSlice()
+	return res, errs()
+}
+
+// AsMapE is a variant of AsMap returning the errors (joined) instead of passing them to the error handler
+func (t stepsTransformer[T, IT]) AsMapE() (map[any]any, error) {
+	t, errs := t.withErrorCollector()
+	res := t.AsMap()
+	return res, errs()
+}
+
+// AsCsvE is a variant of AsCsv returning the errors (joined) instead of passing them to the error handler
+func (t stepsTransformer[T, IT]) AsCsvE() (string, error) {
+	t, errs := t.withErrorCollector()
+	res := t.AsCsv()
+	return res, errs()
+}
+
+// ToStreamingCsvE is a variant of ToStreamingCsv returning the errors (joined) instead of passing them to the error handler
+func (t stepsTransformer[T, IT]) ToStreamingCsvE(writer io.Writer) error {
+	t, errs := t.withErrorCollector()
+	t.ToStreamingCsv(writer)
+	return errs()
+}
+
+// AsJsonE is a variant of AsJson returning the errors (joined) instead of passing them to the error handler
+func (t stepsTransformer[T, IT]) AsJsonE() (string, error) {
+	t, errs := t.withErrorCollector()
+	res := t.AsJson()
+	return res, errs()
+}
+
+// ToStreamingJsonE is a variant of ToStreamingJson returning the errors (joined) instead of passing them to the error handler
+func (t stepsTransformer[T, IT]) ToStreamingJsonE(writer io.Writer) error {
+	t, errs := t.withErrorCollector()
+	t.ToStreamingJson(writer)
+	return errs()
+}
+
+// outTypeValidation checks the requested output type against the validated output type of the transformer.
+// Unknown or interface output types are only checked when the outputs are type asserted.
+func outTypeValidation[OUT any](outType reflect.Type) error {
+	requestedType := reflect.TypeFor[OUT]()
+	if outType == nil || outType.Kind() == reflect.Interface || outType.AssignableTo(requestedType) {
+		return nil
+	}
+	return fmt.Errorf("%w [%s!=%s]", ErrIncompatibleOutType, outType.String(), requestedType.String())
+}
+
+// asType type asserts a single output value
+func asType[OUT any](v any) (OUT, error) {
+	var out OUT
+	if v == nil {
+		return out, nil
+	}
+	out, ok := v.(OUT)
+	if !ok {
+		return out, fmt.Errorf("%w [%T!=%s]", ErrIncompatibleOutType, v, reflect.TypeFor[OUT]().String())
+	}
+	return out, nil
+}
+
+// AsTypedRange returns the transformer output as a typed single value iterator ready to be used by the range keyword.
+// The output type is validated against the output of the last step, and an error is returned when they don't match.
+// Unknown output types (like the output of [Merge]) are type asserted during the iteration,
+// and the iteration stops at the first mismatching output passing [ErrIncompatibleOutType] to the error handler.
+func AsTypedRange[OUT, T any, IT inputType[T]](t stepsTransformer[T, IT]) (iter.Seq[OUT], error) {
+	if t.error != nil {
+		return nil, errWithTrName(t, t.error)
+	}
+	if err := outTypeValidation[OUT](t.outTypes[0]); err != nil {
+		return nil, errWithTrName(t, err)
+	}
+
+	return func(yield func(OUT) bool) {
+		for v := range t.AsRange() {
+			out, err := asType[OUT](v)
+			if err != nil {
+				handleErrWithTrName(t, err, t.options.ErrorHandler)
+				return
+			}
+			if !yield(out) {
+				return
+			}
+		}
+	}, nil
+}
+
+// AsTypedSlice collects the transformer output into a typed slice.
+// The output type is validated the same way as in [AsTypedRange].
+func AsTypedSlice[OUT, T any, IT inputType[T]](t stepsTransformer[T, IT]) ([]OUT, error) {
+	seq, err := AsTypedRange[OUT](t)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []OUT{}
+	for v := range seq {
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+// AsTypedMap collects the transformer output into a typed map.
+// The keys are the first and the values are the second outputs of the last step,
+// or the keys are the keys of the inputs when the last step has a single output
+// (the indexes of the inputs, or the keys of the iter.Seq2 input like the keys of [TransformSeq2]).
+// The output types are validated the same way as in [AsTypedRange].
+func AsTypedMap[K comparable, V, T any, IT inputType[T]](t stepsTransformer[T, IT]) (map[K]V, error) {
+	if t.error != nil {
+		return nil, errWithTrName(t, t.error)
+	}
+	keyType, valueType := t.keyType, t.outTypes[0]
+	if t.outTypes[1] != nil {
+		keyType, valueType = t.outTypes[0], t.outTypes[1]
+	}
+	if err := outTypeValidation[K](keyType); err != nil {
+		return nil, errWithTrName(t, err)
+	}
+	if err := outTypeValidation[V](valueType); err != nil {
+		return nil, errWithTrName(t, err)
+	}
+
+	res := map[K]V{}
+	for k, v := range t.AsIndexedRange() {
+		key, err := asType[K](k)
+		if err != nil {
+			handleErrWithTrName(t, err, t.options.ErrorHandler)
+			break
+		}
+		value, err := asType[V](v)
+		if err != nil {
+			handleErrWithTrName(t, err, t.options.ErrorHandler)
+			break
+		}
+		res[key] = value
+	}
+	return res, nil
+}
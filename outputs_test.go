@@ -5,6 +5,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -332,6 +338,123 @@ func TestAsIndexedRange_WithChan(t *testing.T) {
 	}
 }
 
+func TestAsRange_WithSeq(t *testing.T) {
+	for _, sc := range []struct {
+		name           string
+		input          []int
+		steps          StepsBranch
+		expectedOutput []any
+	}{
+		{
+			name: "empty_input",
+		}, {
+			name:           "empty_steps",
+			input:          []int{1, 2, 3},
+			steps:          Steps(),
+			expectedOutput: []any{1, 2, 3},
+		}, {
+			name:           "input_processed",
+			input:          []int{1, 2, 3, 4, 5},
+			steps:          Steps(filterFn, mapFn),
+			expectedOutput: []any{3, 5},
+		}, {
+			name:           "last_item_aggregated",
+			input:          []int{1, 2, 3, 4, 5},
+			steps:          Steps(mapFn).Aggregate(Sum[int]()),
+			expectedOutput: []any{20},
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			res := Transform[int](slices.Values(sc.input), WithErrorHandler(expectsError(t, false))).
+				With(sc.steps).
+				AsSlice()
+
+			assert.Equal(t, sc.expectedOutput, nilIfEmpty(res))
+		})
+	}
+}
+
+func TestAsIndexedRange_WithSeq2(t *testing.T) {
+	input := []string{"a", "bb", "ccc", "dddd"}
+
+	t.Run("keys_passed", func(t *testing.T) {
+		res := TransformSeq2(slices.All(input), WithErrorHandler(expectsError(t, false))).
+			WithSteps(
+				Map(func(in string) (int, error) {
+					return len(in), nil
+				}),
+				Filter(func(in int) (bool, error) {
+					return in != 2, nil
+				}),
+			).
+			AsMap()
+
+		assert.Equal(t, map[any]any{0: 1, 2: 3, 3: 4}, res)
+	})
+
+	t.Run("map_input", func(t *testing.T) {
+		res := TransformSeq2(maps.All(map[string]int{"a": 1, "b": 2})).
+			WithSteps(
+				Map(func(in int) (int, error) {
+					return in * 10, nil
+				}),
+			).
+			AsMap()
+
+		assert.Equal(t, map[any]any{"a": 10, "b": 20}, res)
+	})
+
+	t.Run("chained_transformers", func(t *testing.T) {
+		first := Transform[string](input).
+			WithSteps(
+				Map1To2(func(in string) (string, int, error) {
+					return in[:1], len(in), nil
+				}),
+			).
+			AsIndexedRange()
+
+		res := Transform[any](first).
+			WithSteps(
+				Map(func(in any) (string, error) {
+					return strings.Repeat("*", in.(int)), nil
+				}),
+			).
+			AsMap()
+
+		assert.Equal(t, map[any]any{"a": "*", "b": "**", "c": "***", "d": "****"}, res)
+	})
+
+	t.Run("early_break", func(t *testing.T) {
+		var pulled []string
+		seq := func(yield func(int, string) bool) {
+			for idx, v := range input {
+				pulled = append(pulled, v)
+				if !yield(idx, v) {
+					return
+				}
+			}
+		}
+
+		var res []any
+		for k := range TransformSeq2(seq).WithSteps(Take[string](10)).AsIndexedRange() {
+			res = append(res, k)
+			if len(res) == 2 {
+				break
+			}
+		}
+
+		assert.Equal(t, []any{0, 1}, res)
+		assert.Equal(t, []string{"a", "bb", "ccc"}, pulled)
+	})
+}
+
+func nilIfEmpty(items []any) []any {
+	if len(items) == 0 {
+		return nil
+	}
+	return items
+}
+
 func TestAsRange_WithSlice_WithoutErrorHandler(t *testing.T) {
 	transformer := stepsTransformer[int, []int]{
 		input: []int{1, 2, 3, 4, 5},
@@ -683,6 +806,393 @@ func TestToStreamingJson(t *testing.T) {
 	assert.Equal(t, expected, buf.String())
 }
 
+func TestErrorReturningOutputs(t *testing.T) {
+	persons := []testPerson{
+		{Name: "John Doe", Code: 11},
+		{Name: "Jane Doe", Code: 22},
+		{Name: "Doe", Code: 33},
+	}
+	failingCodes := func(codes ...int) StepWrapper {
+		return Do(func(in testPerson) error {
+			if slices.Contains(codes, in.Code) {
+				return fmt.Errorf("invalid code %d", in.Code)
+			}
+			return nil
+		})
+	}
+	invalidSteps := []StepWrapper{
+		Map(func(in testPerson) (string, error) {
+			return in.Name, nil
+		}),
+		failingCodes(),
+	}
+	outputs := map[string]func(stepsTransformer[testPerson, []testPerson]) (any, error){
+		"AsSliceE": func(t stepsTransformer[testPerson, []testPerson]) (any, error) {
+			return t.AsSliceE()
+		},
+		"AsMapE": func(t stepsTransformer[testPerson, []testPerson]) (any, error) {
+			return t.AsMapE()
+		},
+		"AsCsvE": func(t stepsTransformer[testPerson, []testPerson]) (any, error) {
+			return t.AsCsvE()
+		},
+		"ToStreamingCsvE": func(t stepsTransformer[testPerson, []testPerson]) (any, error) {
+			var buf bytes.Buffer
+			err := t.ToStreamingCsvE(&buf)
+			return buf.String(), err
+		},
+		"AsJsonE": func(t stepsTransformer[testPerson, []testPerson]) (any, error) {
+			return t.AsJsonE()
+		},
+		"ToStreamingJsonE": func(t stepsTransformer[testPerson, []testPerson]) (any, error) {
+			var buf bytes.Buffer
+			err := t.ToStreamingJsonE(&buf)
+			return buf.String(), err
+		},
+	}
+
+	for _, sc := range []struct {
+		name        string
+		steps       []StepWrapper
+		options     []func(*TransformerOptions)
+		expected    map[string]any
+		expectedErr string
+	}{
+		{
+			name:  "without_errors",
+			steps: []StepWrapper{failingCodes()},
+			expected: map[string]any{
+				"AsSliceE":         []any{persons[0], persons[1], persons[2]},
+				"AsMapE":           map[any]any{0: persons[0], 1: persons[1], 2: persons[2]},
+				"AsCsvE":           "name,dob,code\nJohn Doe,,11\nJane Doe,,22\nDoe,,33\n",
+				"ToStreamingCsvE":  "name,dob,code\nJohn Doe,,11\nJane Doe,,22\nDoe,,33\n",
+				"AsJsonE":          `[{"name":"John Doe","code":11},{"name":"Jane Doe","code":22},{"name":"Doe","code":33}]`,
+				"ToStreamingJsonE": "{\"name\":\"John Doe\",\"code\":11}\n{\"name\":\"Jane Doe\",\"code\":22}\n{\"name\":\"Doe\",\"code\":33}\n",
+			},
+		}, {
+			name:    "step_errors_joined",
+			steps:   []StepWrapper{failingCodes(11, 33)},
+			options: []func(*TransformerOptions){WithErrorPolicy(Continue), WithName(tfName)},
+			expected: map[string]any{
+				"AsSliceE":         []any{persons[1]},
+				"AsMapE":           map[any]any{1: persons[1]},
+				"AsCsvE":           "name,dob,code\nJane Doe,,22\n",
+				"ToStreamingCsvE":  "name,dob,code\nJane Doe,,22\n",
+				"AsJsonE":          `[{"name":"Jane Doe","code":22}]`,
+				"ToStreamingJsonE": "{\"name\":\"Jane Doe\",\"code\":22}\n",
+			},
+			expectedErr: "[TFName] invalid code 11\n[TFName] invalid code 33",
+		}, {
+			name:  "validation_error",
+			steps: invalidSteps,
+			expected: map[string]any{
+				"AsSliceE":         []any{},
+				"AsMapE":           map[any]any{},
+				"AsCsvE":           "",
+				"ToStreamingCsvE":  "",
+				"AsJsonE":          "[]",
+				"ToStreamingJsonE": "",
+			},
+			expectedErr: "step validation failed [Do:2]: incompatible input argument type [string!=steps.testPerson:1]",
+		},
+	} {
+		for name, output := range outputs {
+			t.Run(sc.name+"_"+name, func(t *testing.T) {
+				options := append([]func(*TransformerOptions){WithErrorHandler(func(err error) {
+					assert.Fail(t, "unexpected error handler call", err)
+				})}, sc.options...)
+				transformer := Transform[testPerson](persons, options...).WithSteps(sc.steps...)
+
+				actual, err := output(transformer)
+				assert.Equal(t, sc.expected[name], actual)
+				if sc.expectedErr != "" {
+					assert.EqualError(t, err, sc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+				}
+			})
+		}
+	}
+}
+
+func TestErrorReturningOutputs_SourceAndPipe(t *testing.T) {
+	unexpectedHandlerCall := WithErrorHandler(func(err error) {
+		assert.Fail(t, "unexpected error handler call", err)
+	})
+	toName := Map(func(in testPerson) (string, error) {
+		return in.Name, nil
+	})
+
+	t.Run("missing_file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "missing.csv")
+		actual, err := TransformSource(CsvSource[testPerson](File(path), false), WithName(tfName), unexpectedHandlerCall).
+			WithSteps(toName).
+			AsSliceE()
+
+		assert.Empty(t, actual)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		assert.ErrorContains(t, err, "[TFName]")
+	})
+
+	t.Run("source_decode_error", func(t *testing.T) {
+		input := strings.NewReader("{\"name\":\"John\",\"code\":1}\n{\"name\":\"Jane\",\"code\":\"x\"}\n")
+		actual, err := TransformSource(JsonSource[testPerson](input), unexpectedHandlerCall).
+			WithSteps(toName).
+			AsJsonE()
+
+		assert.Equal(t, `["John"]`, actual)
+		var decodeErr *DecodeError
+		assert.ErrorAs(t, err, &decodeErr)
+	})
+
+	t.Run("piped_errors", func(t *testing.T) {
+		failOn := func(value int) StepWrapper {
+			return Map(func(in int) (int, error) {
+				if in == value {
+					return 0, errStep
+				}
+				return in, nil
+			})
+		}
+
+		actual, err := Transform[int]([]int{1, 2, 3, 4}, WithName("first"), WithErrorPolicy(Continue), unexpectedHandlerCall).
+			WithSteps(failOn(1)).
+			Then(Steps(failOn(3)), WithName("second"), unexpectedHandlerCall).
+			AsSliceE()
+
+		assert.Equal(t, []any{2}, actual)
+		assert.EqualError(t, err, "[second] [first] step error\n[second] step error")
+	})
+
+	t.Run("streaming_producer_error", func(t *testing.T) {
+		producer := func(opts TransformerOptions) chan testPerson {
+			inputCh := make(chan testPerson)
+			go func() {
+				defer close(inputCh)
+				inputCh <- testPerson{Name: "John"}
+				opts.ErrorHandler(errors.New("producer error"))
+			}()
+			return inputCh
+		}
+
+		actual, err := TransformFn[testPerson](producer, unexpectedHandlerCall).
+			WithSteps(toName).
+			AsSliceE()
+
+		assert.Equal(t, []any{"John"}, actual)
+		assert.EqualError(t, err, "producer error")
+	})
+
+	t.Run("missing_file_of_input_function", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "missing.csv")
+		actual, err := TransformFn[testPerson](FromCsv[testPerson](File(path)), unexpectedHandlerCall).
+			WithSteps(toName).
+			AsSliceE()
+
+		assert.Empty(t, actual)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("streaming_decode_error", func(t *testing.T) {
+		input := strings.NewReader("{\"name\":\"John\",\"code\":1}\n{\"name\":\"Jane\",\"code\":\"x\"}\n{\"name\":\"Bob\",\"code\":3}\n")
+		actual, err := TransformFn[testPerson](FromStreamingJson[testPerson](input), unexpectedHandlerCall).
+			WithSteps(toName).
+			AsSliceE()
+
+		assert.Equal(t, []any{"John", "Bob"}, actual)
+		var decodeErr *DecodeError
+		assert.ErrorAs(t, err, &decodeErr)
+		assert.Equal(t, 2, decodeErr.Line)
+	})
+
+	t.Run("streaming_input_canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		input := strings.NewReader("name,code\nJohn,1\n")
+
+		_, err := TransformFn[testPerson](FromStreamingCsv[testPerson](input, false), WithContext(ctx), unexpectedHandlerCall).
+			WithSteps(toName).
+			AsSliceE()
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestAsTypedRange(t *testing.T) {
+	toString := Map(func(in int) (string, error) {
+		return strconv.Itoa(in), nil
+	})
+	merged := func(evenBranch StepsBranch) stepsTransformer[int, []int] {
+		return Transform[int]([]int{1, 2, 3}).
+			WithSteps(
+				Split(func(in int) (uint8, error) {
+					return uint8(in % 2), nil
+				}),
+				WithBranches[int](evenBranch, Steps(toString)),
+				Merge(),
+			)
+	}
+
+	for _, sc := range []struct {
+		name          string
+		transformer   stepsTransformer[int, []int]
+		expected      []string
+		expectedErr   error
+		expectedIOErr error
+	}{
+		{
+			name: "matching_output_type",
+			transformer: Transform[int]([]int{1, 2}).
+				WithSteps(Map(func(in int) (string, error) {
+					return strconv.Itoa(in * 10), nil
+				})),
+			expected: []string{"10", "20"},
+		}, {
+			name: "different_output_type",
+			transformer: Transform[int]([]int{1, 2}).
+				WithSteps(Filter(func(in int) (bool, error) {
+					return true, nil
+				})),
+			expectedErr: ErrIncompatibleOutType,
+		}, {
+			name: "validation_error",
+			transformer: Transform[int]([]int{1, 2}).
+				WithSteps(toString, toString),
+			expectedErr: ErrIncompatibleInArgType,
+		}, {
+			name:        "unknown_output_type_asserted",
+			transformer: merged(Steps(toString)),
+			expected:    []string{"1", "2", "3"},
+		}, {
+			name:          "unknown_output_type_mismatch",
+			transformer:   merged(Steps()),
+			expected:      []string{"1"},
+			expectedIOErr: ErrIncompatibleOutType,
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			var iterErr error
+			sc.transformer.options.ErrorHandler = func(err error) {
+				iterErr = err
+			}
+
+			seq, err := AsTypedRange[string](sc.transformer)
+			if sc.expectedErr != nil {
+				assert.ErrorIs(t, err, sc.expectedErr)
+				assert.Nil(t, seq)
+				return
+			}
+			assert.NoError(t, err)
+
+			actual := []string{}
+			for v := range seq {
+				actual = append(actual, v)
+			}
+			assert.Equal(t, sc.expected, actual)
+			if sc.expectedIOErr != nil {
+				assert.ErrorIs(t, iterErr, sc.expectedIOErr)
+			} else {
+				assert.NoError(t, iterErr)
+			}
+		})
+	}
+}
+
+func TestAsTypedSlice(t *testing.T) {
+	actual, err := AsTypedSlice[int](Transform[int]([]int{1, 2, 3}).WithSteps())
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, actual)
+
+	actual, err = AsTypedSlice[int](Transform[int]([]int{1, 2, 3}).
+		With(Aggregate(Sum[int]())))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{6}, actual)
+
+	actual, err = AsTypedSlice[int](Transform[string]([]string{"1"}, WithName(tfName)).WithSteps())
+	assert.ErrorIs(t, err, ErrIncompatibleOutType)
+	assert.EqualError(t, err, "[TFName] incompatible output type [string!=int]")
+	assert.Nil(t, actual)
+}
+
+func TestAsTypedMap(t *testing.T) {
+	for _, sc := range []struct {
+		name        string
+		actual      func() (any, error)
+		expected    any
+		expectedErr error
+	}{
+		{
+			name: "indexed_values",
+			actual: func() (any, error) {
+				return AsTypedMap[int, string](Transform[string]([]string{"a", "b"}).WithSteps())
+			},
+			expected: map[int]string{0: "a", 1: "b"},
+		}, {
+			name: "key_value_outputs",
+			actual: func() (any, error) {
+				return AsTypedMap[string, int](Transform[int]([]int{1, 2}).WithSteps(
+					StepWrapper{
+						Name: "keyValue",
+						StepFn: func(in StepInput) StepOutput {
+							return StepOutput{Args: Args{strconv.Itoa(in.Args[0].(int)), in.Args[0]}, ArgsLen: 2}
+						},
+						Validate: func(ArgTypes) (ArgTypes, error) {
+							return ArgTypes{reflect.TypeFor[string](), reflect.TypeFor[int]()}, nil
+						},
+					},
+				))
+			},
+			expected: map[string]int{"1": 1, "2": 2},
+		}, {
+			name: "different_key_type",
+			actual: func() (any, error) {
+				return AsTypedMap[string, string](Transform[string]([]string{"a"}).WithSteps())
+			},
+			expectedErr: ErrIncompatibleOutType,
+		}, {
+			name: "different_value_type",
+			actual: func() (any, error) {
+				return AsTypedMap[int, int](Transform[string]([]string{"a"}).WithSteps())
+			},
+			expectedErr: ErrIncompatibleOutType,
+		}, {
+			name: "seq2_keys",
+			actual: func() (any, error) {
+				return AsTypedMap[string, int](TransformSeq2(maps.All(map[string]int{"a": 1, "b": 2})).WithSteps())
+			},
+			expected: map[string]int{"a": 1, "b": 2},
+		}, {
+			name: "different_seq2_key_type",
+			actual: func() (any, error) {
+				return AsTypedMap[int, int](TransformSeq2(maps.All(map[string]int{"a": 1})).WithSteps())
+			},
+			expectedErr: ErrIncompatibleOutType,
+		}, {
+			name: "piped_seq2_keys",
+			actual: func() (any, error) {
+				return AsTypedMap[string, int](TransformSeq2(maps.All(map[string]int{"a": 1, "b": 2})).
+					WithSteps(Map(func(in int) (int, error) {
+						return in * 10, nil
+					})).
+					Then(Steps(Map(func(in int) (int, error) {
+						return in + 1, nil
+					}))))
+			},
+			expected: map[string]int{"a": 11, "b": 21},
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			actual, err := sc.actual()
+			if sc.expectedErr != nil {
+				assert.ErrorIs(t, err, sc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, sc.expected, actual)
+		})
+	}
+}
+
 func optsWithErrHandler(errHandler func(error)) TransformerOptions {
 	opts := opts
 	opts.ErrorHandler = errHandler
@@ -815,3 +1325,96 @@ func Example_stepsTransformer_ToStreamingJson() {
 	// Output: {"ID":1,"Name":"John Doe"}
 	// {"ID":2,"Name":"Jane Doe"}
 }
+
+func ExampleAsTypedRange() {
+	seq, err := AsTypedRange[string](Transform[int]([]int{1, 2, 3}).
+		WithSteps(
+			Map(func(in int) (string, error) {
+				return strings.Repeat("*", in), nil
+			}),
+		))
+	if err != nil {
+		panic(err)
+	}
+
+	for s := range seq {
+		fmt.Print(s, " ")
+	}
+	// Output: * ** ***
+}
+
+func ExampleAsTypedSlice() {
+	_, err := AsTypedSlice[int](Transform[int]([]int{1, 2, 3}).
+		WithSteps(
+			Map(func(in int) (string, error) {
+				return strings.Repeat("*", in), nil
+			}),
+		))
+
+	fmt.Println(err)
+	// Output: incompatible output type [string!=int]
+}
+
+func ExampleAsTypedMap() {
+	res, err := AsTypedMap[int, string](Transform[string]([]string{"h", "e", "l", "l", "o"}).
+		WithSteps())
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(res)
+	// Output: map[0:h 1:e 2:l 3:l 4:o]
+}
+
+func Example_stepsTransformer_AsSliceE() {
+	res, err := Transform[string]([]string{"1", "x", "3", "y"}, WithErrorPolicy(Continue)).
+		WithSteps(
+			Map(func(in string) (int, error) {
+				return strconv.Atoi(in)
+			}),
+		).
+		AsSliceE()
+
+	fmt.Println(res)
+	fmt.Println(err)
+	// Output:
+	// [1 3]
+	// strconv.Atoi: parsing "x": invalid syntax
+	// strconv.Atoi: parsing "y": invalid syntax
+}
+
+func Example_stepsTransformer_AsMapE() {
+	res, err := Transform[string]([]string{"1", "x", "3"}).
+		WithSteps(
+			Map(func(in string) (int, error) {
+				return strconv.Atoi(in)
+			}),
+		).
+		AsMapE()
+
+	fmt.Println(res)
+	fmt.Println(err)
+	// Output:
+	// map[0:1]
+	// strconv.Atoi: parsing "x": invalid syntax
+}
+
+func Example_stepsTransformer_AsCsvE() {
+	fmt.Println("see AsSliceE")
+	// Output: see AsSliceE
+}
+
+func Example_stepsTransformer_ToStreamingCsvE() {
+	fmt.Println("see AsSliceE")
+	// Output: see AsSliceE
+}
+
+func Example_stepsTransformer_AsJsonE() {
+	fmt.Println("see AsSliceE")
+	// Output: see AsSliceE
+}
+
+func Example_stepsTransformer_ToStreamingJsonE() {
+	fmt.Println("see AsSliceE")
+	// Output: see AsSliceE
+}
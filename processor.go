@@ -2,18 +2,25 @@ package steps
 
 import (
 	"fmt"
+	"iter"
 	"reflect"
-	"sync"
+	"runtime/debug"
+	"strings"
+	"time"
 )
 
 func getValidatedSteps[T any](stepWrappers []StepWrapper) ([]StepFn, ArgTypes, error) {
+	return validateSteps(stepWrappers, ArgTypes{reflect.TypeFor[T]()})
+}
+
+// validateSteps validates the steps starting with the given input types
+func validateSteps(stepWrappers []StepWrapper, inTypes ArgTypes) ([]StepFn, ArgTypes, error) {
 	if len(stepWrappers) == 0 {
 		return nil, ArgTypes{}, nil
 	}
 	validSteps := make([]StepFn, 0, len(stepWrappers))
 
-	transformInputType := reflect.TypeFor[T]()
-	outTypes := ArgTypes{transformInputType}
+	outTypes := inTypes
 
 	for pos, wrapper := range stepWrappers {
 		if len(wrapper.Name) == 0 || wrapper.StepFn == nil {
@@ -37,14 +44,12 @@ func (so *StepOutput) Reset() {
 		so.Args[i] = nil
 	}
 	so.ArgsLen = 0
+	so.MultiArgs = nil
+	so.MultiErrors = nil
+	so.MultiSeq = nil
 	so.Error = nil
 	so.Skip = false
-}
-
-var poolOut sync.Pool = sync.Pool{
-	New: func() any {
-		return &StepOutput{}
-	},
+	so.Done = false
 }
 
 func process[V any](val V, yield func(any) bool, transformer *transformer, isLastItem bool) (bool, bool, error) {
@@ -52,109 +57,507 @@ func process[V any](val V, yield func(any) bool, transformer *transformer, isLas
 		return false, !yield(val), nil
 	}
 
-	out, skipped := getProcessResult(val, transformer)
-	defer func() {
-		out.Reset()
-		poolOut.Put(out)
-	}()
+	return transformer.processItem(val, isLastItem, func(out StepOutput) bool {
+		return yield(out.Args[0])
+	})
+}
+
+func processIndexed[V any](key any, val V, yield func(any, any) bool, transformer *transformer, isLastItem bool) (bool, bool, error) {
+	if transformer == nil {
+		return false, !yield(key, val), nil
+	}
+
+	return transformer.processItem(val, isLastItem, func(out StepOutput) bool {
+		if out.ArgsLen > 1 {
+			return yield(out.Args[0], out.Args[1])
+		}
+		return yield(key, out.Args[0])
+	})
+}
+
+func processFlush(yield func(any) bool, transformer *transformer, isLastItem bool) (bool, error) {
+	return transformer.flushInput(isLastItem, func(out StepOutput) bool {
+		return yield(out.Args[0])
+	})
+}
+
+func processFlushIndexed(key any, yield func(any, any) bool, transformer *transformer, isLastItem bool) (bool, error) {
+	return transformer.flushInput(isLastItem, func(out StepOutput) bool {
+		if out.ArgsLen > 1 {
+			return yield(out.Args[0], out.Args[1])
+		}
+		return yield(key, out.Args[0])
+	})
+}
+
+// processItem runs the steps and the aggregator on a single input item.
+// It returns whether the item was skipped (nothing was emitted), whether the processing is terminated
+// (the consumer stopped or the last item was processed) and the error of the processing.
+func (t *transformer) processItem(val any, isLastItem bool, emit func(StepOutput) bool) (bool, bool, error) {
+	var emitted bool
+	emitOut := func(out StepOutput) bool {
+		emitted = true
+		return emit(out)
+	}
+
+	in := StepInput{
+		Args:               Args{val},
+		ArgsLen:            1,
+		TransformerOptions: t.options,
+	}
+	if t.piped {
+		// the piped transformer outputs are passed with all of their arguments
+		out := val.(StepOutput)
+		in.Args, in.ArgsLen = out.Args, out.ArgsLen
+	}
+	terminated, err := t.processRecovered(0, in, emitOut)
+	if terminated || err != nil {
+		return false, terminated, err
+	}
+	if !isLastItem && !t.done {
+		return !emitted, false, nil
+	}
+
+	if err = t.finish(emitOut); err != nil {
+		return false, false, err
+	}
+	return !emitted, true, nil
+}
+
+// flushInput flushes the steps whose flush time passed while the input is waiting for the next item,
+// or finishes the processing when the input is exhausted after it's last item was already processed.
+// It returns whether the processing is terminated and the error of the processing.
+func (t *transformer) flushInput(isLastItem bool, emit func(StepOutput) bool) (bool, error) {
+	if isLastItem {
+		return true, t.finish(emit)
+	}
+	if terminated, err := t.flushExpired(emit); terminated || err != nil || !t.done {
+		return terminated, err
+	}
+	return true, t.finish(emit)
+}
+
+// finish flushes the steps and emits the aggregated value once the input is exhausted
+func (t *transformer) finish(emit func(StepOutput) bool) error {
+	if terminated, err := t.flush(emit); terminated || err != nil {
+		return err
+	}
+	if t.aggregatedItems > 0 {
+		emit(t.lastAggregatedValue)
+	}
+	return nil
+}
+
+// processFrom runs the steps starting at pos, and passes the result to the aggregator or emits it.
+func (t *transformer) processFrom(pos int, in StepInput, emit func(StepOutput) bool) (bool, error) {
+	if pos == len(t.steps) {
+		return t.aggregateOrEmit(in, emit)
+	}
+
+	if err := t.options.Ctx.Err(); err != nil {
+		return false, err
+	}
+	stepFn, _ := t.stepFn(pos)
+	return t.processOutput(pos, in, t.runStep(pos, stepFn, in), emit)
+}
+
+// processRecovered runs the steps starting at pos like processFrom, and handles the panic of a step like it's error.
+// The panics are recovered once per item (and once per output of the steps with multiple outputs) instead of once per step,
+// so a panicking step abandons the rest of the item.
+func (t *transformer) processRecovered(pos int, in StepInput, emit func(StepOutput) bool) (terminated bool, err error) {
+	defer t.recoverPanic(&terminated, &err)
+	return t.processFrom(pos, in, emit)
+}
+
+// runStep calls the step at pos (the aggregator is after the last step).
+// The running step is recorded, so it's panic could be recovered and reported with it's input (see [transformer.recoverPanic]).
+func (t *transformer) runStep(pos int, stepFn StepFn, in StepInput) StepOutput {
+	t.running = runningStep{pos: pos, args: in.Args, argsLen: in.ArgsLen, isSet: true}
+	out := stepFn(in)
+	t.running.isSet = false
+	return out
+}
+
+// recoverPanic recovers the panic of the running step (see [transformer.runStep]), and applies the error policy of the step on it.
+// Other panics (like the panic of the consumer) are not recovered.
+func (t *transformer) recoverPanic(terminated *bool, err *error) {
+	if !t.running.isSet {
+		return
+	}
+	r := recover()
+	if r == nil {
+		return
+	}
+	running := t.running
+	t.running.isSet = false
+	in := StepInput{
+		Args:               running.args,
+		ArgsLen:            running.argsLen,
+		TransformerOptions: t.options,
+	}
+	*terminated, *err = false, t.handleError(running.pos, in, newStepPanicError(t.stepName(running.pos), running.pos, r))
+}
+
+// processOutput passes the output of the step at pos to the next steps.
+// Steps emitting multiple outputs are continuing the processing for each output separately,
+// and the error policy is applied on each failed output (like the failed inputs of ParallelMap) in their order.
+// The lazily pulled outputs are not pulled further when a next step is done (see [StepOutput.Done]).
+func (t *transformer) processOutput(pos int, stepIn StepInput, out StepOutput, emit func(StepOutput) bool) (bool, error) {
+	if out.Done {
+		defer func() {
+			t.done = true
+		}()
+	}
+	_, next := t.stepFn(pos)
 	if out.Error != nil {
-		return false, false, out.Error
+		// the outputs of a failed step are discarded (also the multiple outputs)
+		return false, t.handleError(t.unfuseError(pos, stepIn, out.Error))
 	}
-	aggOut := transformer.lastAggregatedValue
-	if aggOut != nil {
-		if aggOut.Error != nil {
-			return false, false, aggOut.Error
+	if out.MultiSeq != nil {
+		// the sequence is pulled, so the emit function is not captured by the sequence and it does not escape
+		nextArgs, stop := iter.Pull(out.MultiSeq)
+		defer stop()
+		for {
+			// the sequence is running as the step, so it's panic is reported by the step
+			t.running = runningStep{pos: pos, args: stepIn.Args, argsLen: stepIn.ArgsLen, isSet: true}
+			args, ok := nextArgs()
+			t.running.isSet = false
+			if !ok {
+				return false, nil
+			}
+			in := StepInput{
+				Args:               args,
+				ArgsLen:            out.ArgsLen,
+				TransformerOptions: t.options,
+			}
+			if terminated, err := t.processRecovered(next, in, emit); terminated || err != nil || t.done {
+				return terminated, err
+			}
 		}
-		if isLastItem {
-			return false, yield(aggOut.Args[0]), nil
+	}
+	if out.MultiArgs != nil {
+		for i, args := range out.MultiArgs {
+			in := StepInput{
+				Args:               args,
+				ArgsLen:            out.ArgsLen,
+				TransformerOptions: t.options,
+			}
+			if i < len(out.MultiErrors) && out.MultiErrors[i] != nil {
+				if err := t.handleError(pos, in, t.stepError(pos, out.MultiErrors[i])); err != nil {
+					return false, err
+				}
+				continue
+			}
+			if terminated, err := t.processRecovered(next, in, emit); terminated || err != nil {
+				return terminated, err
+			}
 		}
+		return false, nil
 	}
 
-	if skipped {
-		return true, false, nil
+	if out.Skip {
+		return false, nil
 	}
-	return false, !yield(out.Args[0]), nil
+
+	in := StepInput{
+		Args:               out.Args,
+		ArgsLen:            out.ArgsLen,
+		TransformerOptions: t.options,
+	}
+	return t.processFrom(next, in, emit)
 }
 
-func processIndexed[V any](key any, val V, yield func(any, any) bool, transformer *transformer, isLastItem bool) (bool, bool, error) {
-	if transformer == nil {
-		return false, !yield(key, val), nil
+func (t *transformer) aggregateOrEmit(in StepInput, emit func(StepOutput) bool) (bool, error) {
+	if t.aggregator == nil {
+		return !emit(StepOutput{Args: in.Args, ArgsLen: in.ArgsLen}), nil
 	}
 
-	out, skipped := getProcessResult(val, transformer)
-	defer func() {
-		out.Reset()
-		poolOut.Put(out)
-	}()
+	out := t.runStep(len(t.steps), StepFn(t.aggregator), in)
 	if out.Error != nil {
-		return false, false, out.Error
+		return false, t.handleError(len(t.steps), in, out.Error)
 	}
+	t.lastAggregatedValue = out
+	t.aggregatedItems++
 
-	idx := key
-	v := out.Args[0]
-	if out.ArgsLen > 1 {
-		idx = out.Args[0]
-		v = out.Args[1]
+	shouldEmit, err := t.shouldEmitAggregated(in)
+	if err != nil {
+		return false, t.handleError(len(t.steps), in, err)
+	}
+	if !shouldEmit {
+		return false, nil
 	}
+	return t.emitAggregated(emit), nil
+}
+
+// shouldEmitAggregated checks the emission policy of the aggregator.
+// The panic of the trigger is converted to a [StepPanicError] of the aggregator.
+func (t *transformer) shouldEmitAggregated(in StepInput) (shouldEmit bool, err error) {
+	opts := t.aggregatorOptions
+	switch {
+	case opts.EveryItems > 0 && t.aggregatedItems >= opts.EveryItems:
+		return true, nil
+	case opts.EveryDuration > 0 && t.sinceLastEmission() >= opts.EveryDuration:
+		return true, nil
+	case opts.Trigger != nil:
+		defer func() {
+			if r := recover(); r != nil {
+				shouldEmit, err = false, newStepPanicError(t.aggregatorName, len(t.steps), r)
+			}
+		}()
+		return opts.Trigger(in.Args[0]), nil
+	default:
+		return false, nil
+	}
+}
 
-	aggOut := transformer.lastAggregatedValue
-	if aggOut != nil {
-		if aggOut.Error != nil {
-			return false, false, aggOut.Error
+// sinceLastEmission returns the time passed since the last emission (or since the first aggregated input).
+// The time is only checked when the emission policy depends on it.
+func (t *transformer) sinceLastEmission() time.Duration {
+	now := time.Now()
+	if t.lastEmission.IsZero() {
+		t.lastEmission = now
+	}
+	return now.Sub(t.lastEmission)
+}
+
+// emitAggregated emits an intermediate aggregated value, and resets the aggregator if needed.
+// Without resetting, the aggregated maps are copied, so further aggregation won't change the emitted value.
+func (t *transformer) emitAggregated(emit func(StepOutput) bool) bool {
+	out := t.lastAggregatedValue
+	t.aggregatedItems = 0
+	t.lastEmission = time.Now()
+
+	if t.aggregatorOptions.ResetOnEmit {
+		t.lastAggregatedValue = StepOutput{}
+		if t.aggregatorReset != nil {
+			t.aggregatorReset()
 		}
-		if isLastItem {
-			return false, yield(idx, aggOut.Args[0]), nil
+	} else if v := reflect.ValueOf(out.Args[0]); v.Kind() == reflect.Map && !v.IsNil() {
+		snapshot := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			snapshot.SetMapIndex(iter.Key(), iter.Value())
 		}
+		out.Args[0] = snapshot.Interface()
 	}
 
-	if skipped {
-		return true, false, nil
+	return !emit(out)
+}
+
+// flush emits the outputs buffered by the steps once the input is exhausted.
+// The steps are flushed in order, so the flushed outputs are also passed through the buffering steps that follows.
+func (t *transformer) flush(emit func(StepOutput) bool) (bool, error) {
+	for pos, s := range t.stepWrappers {
+		if s.Flush == nil {
+			continue
+		}
+		if terminated, err := t.flushStep(pos, s.Flush, emit); terminated || err != nil {
+			return terminated, err
+		}
 	}
-	return false, !yield(idx, v), nil
+	return false, nil
 }
 
-func getProcessResult[V any](val V, transformer *transformer) (*StepOutput, bool) {
-	if transformer == nil || (transformer.steps == nil && transformer.aggregator == nil) {
-		out := poolOut.Get().(*StepOutput)
-		out.Args = [4]any{val}
-		out.ArgsLen = 1
-		return out, false
+// flushExpired emits the outputs buffered by the steps whose flush time passed (see [StepWrapper.FlushAt])
+// or which are ready (see [StepWrapper.Ready]), and the aggregated value when the duration of it's emission policy passed,
+// while the input is waiting for the next item.
+func (t *transformer) flushExpired(emit func(StepOutput) bool) (bool, error) {
+	now := time.Now()
+	for pos, s := range t.stepWrappers {
+		var flushFn func(TransformerOptions) StepOutput
+		switch {
+		case s.Flush != nil && s.FlushAt != nil && !s.FlushAt().IsZero() && !s.FlushAt().After(now):
+			flushFn = s.Flush
+		case s.FlushReady != nil && s.Ready != nil && isClosed(s.Ready()):
+			flushFn = s.FlushReady
+		default:
+			continue
+		}
+		if terminated, err := t.flushStep(pos, flushFn, emit); terminated || err != nil {
+			return terminated, err
+		}
+	}
+	if at := t.aggregatedFlushAt(); !at.IsZero() && !at.After(now) {
+		return t.emitAggregated(emit), nil
 	}
+	return false, nil
+}
 
-	in := StepInput{
-		Args:               [4]any{val},
-		ArgsLen:            1,
-		TransformerOptions: transformer.options,
+// hasTimedFlush returns whether any of the steps (or the aggregator) could be flushed on time (see [StepWrapper.FlushAt])
+// or when it's outputs are ready (see [StepWrapper.Ready])
+func (t *transformer) hasTimedFlush() bool {
+	if t.aggregator != nil && t.aggregatorOptions.EveryDuration > 0 {
+		return true
+	}
+	for _, s := range t.stepWrappers {
+		if (s.Flush != nil && s.FlushAt != nil) || (s.FlushReady != nil && s.Ready != nil) {
+			return true
+		}
 	}
+	return false
+}
 
-	var skipped bool
-	out := poolOut.Get().(*StepOutput)
-iterSteps:
-	for _, fn := range transformer.steps {
-		select {
-		case <-transformer.options.Ctx.Done():
-			out.Error = transformer.options.Ctx.Err()
-			return out, false
-		default:
-			*out = fn(in)
-			if out.Skip || out.Error != nil {
-				skipped = true
-				break iterSteps
-			}
+// readyChans returns the channels of the steps waiting for their buffered outputs to be ready (see [StepWrapper.Ready]).
+// The returned slice is reused by the next call.
+func (t *transformer) readyChans() []<-chan struct{} {
+	t.ready = t.ready[:0]
+	for _, s := range t.stepWrappers {
+		if s.FlushReady == nil || s.Ready == nil {
+			continue
+		}
+		if ch := s.Ready(); ch != nil {
+			t.ready = append(t.ready, ch)
+		}
+	}
+	return t.ready
+}
 
-			in = StepInput{
-				Args:               out.Args,
-				ArgsLen:            out.ArgsLen,
-				TransformerOptions: transformer.options,
-			}
+// nextFlush returns the earliest flush time of the steps and the aggregator, or zero when nothing is waiting to be flushed
+func (t *transformer) nextFlush() time.Time {
+	next := t.aggregatedFlushAt()
+	for _, s := range t.stepWrappers {
+		if s.Flush == nil || s.FlushAt == nil {
+			continue
+		}
+		if at := s.FlushAt(); !at.IsZero() && (next.IsZero() || at.Before(next)) {
+			next = at
+		}
+	}
+	return next
+}
+
+// aggregatedFlushAt returns the time when the aggregated value is emitted by the duration of the emission policy,
+// or zero when nothing is aggregated since the last emission
+func (t *transformer) aggregatedFlushAt() time.Time {
+	if t.aggregator == nil || t.aggregatorOptions.EveryDuration <= 0 || t.aggregatedItems == 0 || t.lastEmission.IsZero() {
+		return time.Time{}
+	}
+	return t.lastEmission.Add(t.aggregatorOptions.EveryDuration)
+}
+
+// flushStep emits the outputs buffered by the step at pos using the flush function of the step, and passes them to the next steps
+func (t *transformer) flushStep(pos int, flushFn func(TransformerOptions) StepOutput, emit func(StepOutput) bool) (terminated bool, err error) {
+	defer t.recoverPanic(&terminated, &err)
+	flushStep := func(in StepInput) StepOutput {
+		return flushFn(in.TransformerOptions)
+	}
+	out := t.runStep(pos, flushStep, StepInput{TransformerOptions: t.options})
+	return t.processOutput(pos, StepInput{}, out, emit)
+}
+
+// handleError applies the error policy of the step at pos (the aggregator is after the last step) on the failed item.
+// The dead-lettered inputs of the branch steps are holding the original argument of the branch.
+// It returns the error only when the processing must be stopped (or the dead-letter sink failed, like when the context is done).
+func (t *transformer) handleError(pos int, in StepInput, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	policy := t.options.ErrorPolicy
+	if pos < len(t.stepWrappers) && t.stepWrappers[pos].ErrorPolicy != DefaultErrorPolicy {
+		policy = t.stepWrappers[pos].ErrorPolicy
+	}
+
+	reportErr := func() {
+		if len(t.options.Name) != 0 {
+			err = fmt.Errorf("[%s] %w", t.options.Name, err)
 		}
+		t.options.ErrorHandler(err)
 	}
+	switch policy {
+	case Continue:
+		reportErr()
+		return nil
+	case DeadLetter:
+		if t.options.DeadLetter == nil {
+			reportErr()
+			return nil
+		}
+		return t.options.DeadLetter(t.options.Ctx, FailedItem{
+			Error:    err,
+			Step:     t.stepName(pos),
+			Position: pos + 1,
+			Args:     unwrapBranch(in.Args),
+			ArgsLen:  in.ArgsLen,
+		})
+	default:
+		return err
+	}
+}
+
+// stepError converts the panic recovered from a goroutine of the step at pos (see [recoverStep]) to a [StepPanicError] of the step
+func (t *transformer) stepError(pos int, err error) error {
+	if panicErr, ok := err.(*StepPanicError); ok && panicErr.Position == 0 {
+		return newStepPanicError(t.stepName(pos), pos, panicErr)
+	}
+	return err
+}
 
-	if !skipped && transformer.aggregator != nil {
-		*out = transformer.aggregator(in)
-		transformer.lastAggregatedValue = out
-		skipped = true
+func (t *transformer) stepName(pos int) string {
+	if pos < len(t.stepWrappers) {
+		return t.stepWrappers[pos].Name
 	}
+	return t.aggregatorName
+}
+
+// String formats the failed item like the validation errors, followed by it's input arguments
+func (f FailedItem) String() string {
+	out := strings.Builder{}
+	out.WriteString(fmt.Sprintf("[%s:%d] %v", f.Step, f.Position, f.Error))
+	for i := range f.ArgsLen {
+		out.WriteString(fmt.Sprintf(" \targ%d: %v", i, f.Args[i]))
+	}
+	return out.String()
+}
 
-	return out, skipped
+// recoverStep calls the step function from a goroutine started by a step, and converts it's panic to a [StepPanicError].
+// The step should re-panic the error using [repanic] in the processing goroutine, where it's position is known.
+func recoverStep(stepFn StepFn, in StepInput) (out StepOutput) {
+	defer func() {
+		if r := recover(); r != nil {
+			out = StepOutput{Error: &StepPanicError{Value: r, Stack: debug.Stack()}}
+		}
+	}()
+	return stepFn(in)
+}
+
+// repanic panics with the error recovered by [recoverStep]
+func repanic(err error) {
+	if panicErr, ok := err.(*StepPanicError); ok && panicErr.Position == 0 {
+		panic(panicErr)
+	}
+}
+
+func newStepPanicError(step string, pos int, r any) *StepPanicError {
+	if panicErr, ok := r.(*StepPanicError); ok && panicErr.Position == 0 {
+		panicErr.Step, panicErr.Position = step, pos+1
+		return panicErr
+	}
+	return &StepPanicError{
+		Step:     step,
+		Position: pos + 1,
+		Value:    r,
+		Stack:    debug.Stack(),
+	}
+}
+
+func (e *StepPanicError) Error() string {
+	return fmt.Sprintf("%s [%s:%d]: %v", ErrStepPanicked, e.Step, e.Position, e.Value)
+}
+
+// Unwrap returns ErrStepPanicked, and the panic value when it is an error
+func (e *StepPanicError) Unwrap() []error {
+	if err, ok := e.Value.(error); ok {
+		return []error{ErrStepPanicked, err}
+	}
+	return []error{ErrStepPanicked}
+}
+
+// isClosed returns whether the channel is closed without blocking
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
 }
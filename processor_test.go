@@ -3,8 +3,12 @@ package steps
 import (
 	"context"
 	"errors"
+	"fmt"
+	"iter"
 	"reflect"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -374,3 +378,390 @@ func TestProcessYieldsRawInput_WhenStepsMissing(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 42, *processedValue)
 }
+
+func TestProcess_MultipleOutputs(t *testing.T) {
+	var processedValues []any
+	yield := func(in any) bool {
+		processedValues = append(processedValues, in)
+		return len(processedValues) < 3
+	}
+	duplicateFn := StepWrapper{
+		Name: "duplicate",
+		StepFn: func(in StepInput) StepOutput {
+			return StepOutput{
+				MultiArgs: []Args{in.Args, in.Args},
+				ArgsLen:   1,
+			}
+		},
+	}
+	trn := &transformer{
+		steps:   []StepFn{duplicateFn.StepFn, mapFn.StepFn},
+		options: TransformerOptions{Ctx: context.Background()},
+	}
+
+	skipped, terminated, err := process(1, yield, trn, false)
+	require.NoError(t, err)
+	assert.False(t, skipped)
+	assert.False(t, terminated)
+
+	skipped, terminated, err = process(2, yield, trn, false)
+	require.NoError(t, err)
+	assert.False(t, skipped)
+	assert.True(t, terminated)
+	assert.Equal(t, []any{2, 2, 3}, processedValues)
+}
+
+func TestProcess_FlushesStepsOnLastItem(t *testing.T) {
+	var buffer []Args
+	bufferFn := StepWrapper{
+		Name: "buffer",
+		StepFn: func(in StepInput) StepOutput {
+			buffer = append(buffer, in.Args)
+			return StepOutput{Skip: true}
+		},
+		Flush: func(TransformerOptions) StepOutput {
+			return StepOutput{
+				MultiArgs: buffer,
+				ArgsLen:   1,
+			}
+		},
+	}
+	groupEven := GroupBy(func(in int) (bool, int, error) {
+		return in%2 == 0, in, nil
+	})
+
+	for _, sc := range []struct {
+		name       string
+		aggregator ReducerFn
+		expected   []any
+	}{
+		{
+			name:     "flushed_values_processed",
+			expected: []any{2, 3, 4},
+		}, {
+			name:       "flushed_values_aggregated",
+			aggregator: groupEven.ReducerFn,
+			expected:   []any{map[bool][]int{true: {2, 4}, false: {3}}},
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			buffer = nil
+			groupEven.Reset()
+			var processedValues []any
+			yield := func(in any) bool {
+				processedValues = append(processedValues, in)
+				return true
+			}
+			trn := &transformer{
+				steps:        []StepFn{bufferFn.StepFn, mapFn.StepFn},
+				stepWrappers: []StepWrapper{bufferFn, mapFn},
+				aggregator:   sc.aggregator,
+				options:      TransformerOptions{Ctx: context.Background()},
+			}
+
+			for i, v := range []int{1, 2, 3} {
+				skipped, terminated, err := process(v, yield, trn, i == 2)
+				require.NoError(t, err)
+				assert.Equal(t, i < 2, skipped)
+				assert.Equal(t, i == 2, terminated)
+			}
+			assert.Equal(t, sc.expected, processedValues)
+		})
+	}
+}
+
+func TestProcess_ErrorPolicy(t *testing.T) {
+	errOdd := errors.New("odd input")
+	failOdd := Map(func(in int) (int, error) {
+		if in%2 == 1 {
+			return 0, errOdd
+		}
+		return in, nil
+	})
+
+	for _, sc := range []struct {
+		name               string
+		policy             ErrorPolicy
+		stepPolicy         ErrorPolicy
+		withDeadLetter     bool
+		expected           []any
+		expectedErr        error
+		expectedReported   int
+		expectedDeadLetter []FailedItem
+	}{
+		{
+			name:        "stop_by_default",
+			expectedErr: errOdd,
+		}, {
+			name:        "stop",
+			policy:      Stop,
+			expectedErr: errOdd,
+		}, {
+			name:             "continue",
+			policy:           Continue,
+			expected:         []any{3, 5},
+			expectedReported: 3,
+		}, {
+			name:           "dead_letter",
+			policy:         DeadLetter,
+			withDeadLetter: true,
+			expected:       []any{3, 5},
+			expectedDeadLetter: []FailedItem{
+				{Error: errOdd, Step: "failOdd", Position: 2, Args: Args{1}, ArgsLen: 1},
+				{Error: errOdd, Step: "failOdd", Position: 2, Args: Args{3}, ArgsLen: 1},
+				{Error: errOdd, Step: "failOdd", Position: 2, Args: Args{5}, ArgsLen: 1},
+			},
+		}, {
+			name:             "dead_letter_without_sink",
+			policy:           DeadLetter,
+			expected:         []any{3, 5},
+			expectedReported: 3,
+		}, {
+			name:             "step_policy_overrides_transformer_policy",
+			policy:           Stop,
+			stepPolicy:       Continue,
+			expected:         []any{3, 5},
+			expectedReported: 3,
+		}, {
+			name:        "step_policy_stops_processing",
+			policy:      Continue,
+			stepPolicy:  Stop,
+			expectedErr: errOdd,
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			var processedValues []any
+			yield := func(in any) bool {
+				processedValues = append(processedValues, in)
+				return true
+			}
+			var reported int
+			var deadLetter []FailedItem
+			opts := TransformerOptions{
+				Ctx:         context.Background(),
+				ErrorPolicy: sc.policy,
+				ErrorHandler: func(err error) {
+					assert.ErrorIs(t, err, errOdd)
+					reported++
+				},
+			}
+			if sc.withDeadLetter {
+				opts.DeadLetter = func(_ context.Context, item FailedItem) error {
+					deadLetter = append(deadLetter, item)
+					return nil
+				}
+			}
+			trn := &transformer{
+				steps: []StepFn{mapFn.StepFn, failOdd.StepFn, mapFn.StepFn},
+				stepWrappers: []StepWrapper{
+					{Name: "mapFn"},
+					{Name: "failOdd", ErrorPolicy: sc.stepPolicy},
+					{Name: "mapFn"},
+				},
+				options: opts,
+			}
+
+			var err error
+			for i, v := range []int{0, 1, 2, 3, 4} {
+				if _, _, err = process(v, yield, trn, i == 4); err != nil {
+					break
+				}
+			}
+			assert.Equal(t, sc.expectedErr, err)
+			assert.Equal(t, sc.expected, processedValues)
+			assert.Equal(t, sc.expectedReported, reported)
+			assert.Equal(t, sc.expectedDeadLetter, deadLetter)
+		})
+	}
+}
+
+func TestProcess_DeadLetterChanCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	deadLetterCh := make(chan FailedItem)
+	errFailed := errors.New("failed")
+
+	done := make(chan error)
+	go func() {
+		_, err := Transform[int]([]int{1, 2}, WithContext(ctx), WithErrorPolicy(DeadLetter), WithDeadLetterChan(deadLetterCh)).
+			WithSteps(Map(func(in int) (int, error) {
+				return 0, errFailed
+			})).
+			AsSliceE()
+		done <- err
+	}()
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		require.Fail(t, "the dead-letter send is not stopped by the canceled context")
+	}
+}
+
+func TestFailedItem_String(t *testing.T) {
+	item := FailedItem{
+		Error:    errors.New("failed"),
+		Step:     "Map",
+		Position: 2,
+		Args:     Args{"x", 1},
+		ArgsLen:  2,
+	}
+	assert.Equal(t, "[Map:2] failed \targ0: x \targ1: 1", item.String())
+}
+
+func TestProcess_RecoversStepPanics(t *testing.T) {
+	panicFn := Map(func(in int) (int, error) {
+		if in == 2 {
+			panic("unexpected input")
+		}
+		return in, nil
+	})
+	flushPanicFn := StepWrapper{
+		Name: "flushPanic",
+		StepFn: func(in StepInput) StepOutput {
+			return StepOutput{Args: in.Args, ArgsLen: in.ArgsLen}
+		},
+		Flush: func(TransformerOptions) StepOutput {
+			panic(errors.New("flush failed"))
+		},
+	}
+	aggregatorPanicFn := ReducerFn(func(in StepInput) StepOutput {
+		var m map[int]int
+		m[in.Args[0].(int)] = 1
+		return StepOutput{}
+	})
+
+	for _, sc := range []struct {
+		name             string
+		transformer      *transformer
+		expectedStep     string
+		expectedPosition int
+		expectedValue    string
+	}{
+		{
+			name: "step_panics",
+			transformer: &transformer{
+				steps:        []StepFn{mapFn.StepFn, panicFn.StepFn},
+				stepWrappers: []StepWrapper{{Name: "mapFn"}, {Name: "panicFn"}},
+			},
+			expectedStep:     "panicFn",
+			expectedPosition: 2,
+			expectedValue:    "unexpected input",
+		}, {
+			name: "flush_panics",
+			transformer: &transformer{
+				steps:        []StepFn{flushPanicFn.StepFn},
+				stepWrappers: []StepWrapper{{Name: "flushPanic", Flush: flushPanicFn.Flush}},
+			},
+			expectedStep:     "flushPanic",
+			expectedPosition: 1,
+			expectedValue:    "flush failed",
+		}, {
+			name: "aggregator_panics",
+			transformer: &transformer{
+				steps:          []StepFn{mapFn.StepFn},
+				stepWrappers:   []StepWrapper{{Name: "mapFn"}},
+				aggregator:     aggregatorPanicFn,
+				aggregatorName: "aggregatorPanic",
+			},
+			expectedStep:     "aggregatorPanic",
+			expectedPosition: 2,
+			expectedValue:    "assignment to entry in nil map",
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			sc.transformer.options = TransformerOptions{Ctx: context.Background()}
+			yield := func(any) bool { return true }
+
+			_, _, err := process(1, yield, sc.transformer, true)
+
+			assert.ErrorIs(t, err, ErrStepPanicked)
+			var panicErr *StepPanicError
+			require.ErrorAs(t, err, &panicErr)
+			assert.Equal(t, sc.expectedStep, panicErr.Step)
+			assert.Equal(t, sc.expectedPosition, panicErr.Position)
+			assert.Contains(t, fmt.Sprint(panicErr.Value), sc.expectedValue)
+			assert.Contains(t, string(panicErr.Stack), "processor_test.go")
+		})
+	}
+}
+
+func TestProcess_RecoversPanicsOfMultipleOutputs(t *testing.T) {
+	panicOnTwo := Map(func(in int) (int, error) {
+		if in == 2 {
+			panic("unexpected output")
+		}
+		return in * 10, nil
+	})
+	for _, sc := range []struct {
+		name      string
+		multiStep StepWrapper
+	}{
+		{
+			name: "multi_args",
+			multiStep: FlatMap(func(in int) ([]int, error) {
+				return []int{in, in + 1, in + 2}, nil
+			}),
+		}, {
+			name: "multi_seq",
+			multiStep: FlatMapSeq(func(in int) (iter.Seq[int], error) {
+				return slices.Values([]int{in, in + 1, in + 2}), nil
+			}),
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			var reported []error
+			actual := Transform[int]([]int{1}, WithErrorPolicy(Continue), WithErrorHandler(func(err error) {
+				reported = append(reported, err)
+			})).
+				WithSteps(sc.multiStep, panicOnTwo).
+				AsSlice()
+
+			assert.Equal(t, []any{10, 30}, actual)
+			require.Len(t, reported, 1)
+			assert.ErrorContains(t, reported[0], "step panicked [Map:2]: unexpected output")
+		})
+	}
+
+	t.Run("panicking_sequence", func(t *testing.T) {
+		_, err := Transform[int]([]int{1}).
+			WithSteps(FlatMapSeq(func(in int) (iter.Seq[int], error) {
+				return func(yield func(int) bool) {
+					yield(in)
+					panic("sequence failed")
+				}, nil
+			})).
+			AsSliceE()
+
+		assert.ErrorIs(t, err, ErrStepPanicked)
+		assert.ErrorContains(t, err, "step panicked [FlatMapSeq:1]: sequence failed")
+	})
+
+	t.Run("consumer_panic_is_not_recovered", func(t *testing.T) {
+		assert.PanicsWithValue(t, "consumer failed", func() {
+			for range Transform[int]([]int{1}).WithSteps(panicOnTwo).AsRange() {
+				panic("consumer failed")
+			}
+		})
+	})
+}
+
+func TestProcess_RecoveredPanicsFollowErrorPolicy(t *testing.T) {
+	var reported []error
+	actual := Transform[any]([]any{1, "2", 3}, WithErrorPolicy(Continue), WithErrorHandler(func(err error) {
+		reported = append(reported, err)
+	})).
+		WithSteps(
+			Map(func(in any) (int, error) {
+				return in.(int) * 10, nil
+			}),
+		).
+		AsSlice()
+
+	assert.Equal(t, []any{10, 30}, actual)
+	require.Len(t, reported, 1)
+	assert.ErrorIs(t, reported[0], ErrStepPanicked)
+	assert.ErrorContains(t, reported[0], "step panicked [Map:1]: interface conversion: interface {} is string, not int")
+}
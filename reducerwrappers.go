@@ -1,7 +1,7 @@
 package steps
 
 import (
-	"fmt"
+	"context"
 	"math"
 	"reflect"
 )
@@ -9,27 +9,33 @@ import (
 // GroupBy is an aggregator grouping inputs by comparable values.
 // The grouped values are in a slice.
 func GroupBy[IN0 any, OUT0 comparable, OUT1 any](fn func(in IN0) (OUT0, OUT1, error)) ReducerWrapper {
+	groupByFn := GroupByCtx(func(_ context.Context, in IN0) (OUT0, OUT1, error) {
+		return fn(in)
+	})
+	groupByFn.Name = "GroupBy"
+	return groupByFn
+}
+
+// GroupByCtx is a variant of [GroupBy] passing the context of the transformer to the function
+func GroupByCtx[IN0 any, OUT0 comparable, OUT1 any](fn func(ctx context.Context, in IN0) (OUT0, OUT1, error)) ReducerWrapper {
 	acc := map[OUT0][]OUT1{}
 	return ReducerWrapper{
-		Name: "GroupBy",
+		Name: "GroupByCtx",
 		ReducerFn: func(in StepInput) StepOutput {
-			groupKey, value, err := fn(in.Args[0].(IN0))
+			groupKey, value, err := fn(ctxOf(in), in.Args[0].(IN0))
+			if err != nil {
+				// the failed input is not grouped, so it doesn't affect the groups when the processing continues
+				return StepOutput{Error: err}
+			}
 			acc[groupKey] = append(acc[groupKey], value)
 			return StepOutput{
 				Args:    Args{acc},
 				ArgsLen: 1,
-				Error:   err,
 			}
 		},
 		Validate: func(prevStepOut ArgTypes) (ArgTypes, error) {
-			inArgTypes := ArgTypes{reflect.TypeFor[IN0]()}
-			for i := range maxArgs {
-				if i == 0 && prevStepOut[0] == reflect.TypeFor[SkipFirstArgValidation]() {
-					continue
-				}
-				if prevStepOut[i] != inArgTypes[i] {
-					return ArgTypes{}, fmt.Errorf("%w [%s!=%s:%d]", ErrIncompatibleInArgType, prevStepOut[i].String(), inArgTypes[i].String(), i+1)
-				}
+			if err := argTypesValidation(prevStepOut, ArgTypes{reflect.TypeFor[IN0]()}); err != nil {
+				return ArgTypes{}, err
 			}
 			return ArgTypes{reflect.TypeFor[OUT0](), reflect.TypeFor[OUT1]()}, nil
 		},
@@ -41,28 +47,34 @@ func GroupBy[IN0 any, OUT0 comparable, OUT1 any](fn func(in IN0) (OUT0, OUT1, er
 
 // Fold reduces a series of inputs into a single value using a custom initial value.
 func Fold[IN0 any](initValue IN0, reduceFn func(in1, in2 IN0) (IN0, error)) ReducerWrapper {
+	foldFn := FoldCtx(initValue, func(_ context.Context, in1, in2 IN0) (IN0, error) {
+		return reduceFn(in1, in2)
+	})
+	foldFn.Name = "Fold"
+	return foldFn
+}
+
+// FoldCtx is a variant of [Fold] passing the context of the transformer to the function
+func FoldCtx[IN0 any](initValue IN0, reduceFn func(ctx context.Context, in1, in2 IN0) (IN0, error)) ReducerWrapper {
 	prevValue := initValue
 	return ReducerWrapper{
-		Name: "Fold",
+		Name: "FoldCtx",
 		ReducerFn: func(in StepInput) StepOutput {
 			currentVal := in.Args[0].(IN0)
-			nextValue, err := reduceFn(prevValue, currentVal)
+			nextValue, err := reduceFn(ctxOf(in), prevValue, currentVal)
+			if err != nil {
+				// the failed input is not folded, so the value of the previous inputs is kept
+				return StepOutput{Error: err}
+			}
 			prevValue = nextValue
 			return StepOutput{
 				Args:    Args{nextValue},
 				ArgsLen: 1,
-				Error:   err,
 			}
 		},
 		Validate: func(prevStepOut ArgTypes) (ArgTypes, error) {
-			inArgTypes := ArgTypes{reflect.TypeFor[IN0]()}
-			for i := range maxArgs {
-				if i == 0 && prevStepOut[0] == reflect.TypeFor[SkipFirstArgValidation]() {
-					continue
-				}
-				if prevStepOut[i] != inArgTypes[i] {
-					return ArgTypes{}, fmt.Errorf("%w [%s!=%s:%d]", ErrIncompatibleInArgType, prevStepOut[i].String(), inArgTypes[i].String(), i+1)
-				}
+			if err := argTypesValidation(prevStepOut, ArgTypes{reflect.TypeFor[IN0]()}); err != nil {
+				return ArgTypes{}, err
 			}
 			return ArgTypes{reflect.TypeFor[IN0]()}, nil
 		},
@@ -80,6 +92,14 @@ func Reduce[IN0 any](fn func(in1, in2 IN0) (IN0, error)) ReducerWrapper {
 	return fold
 }
 
+// ReduceCtx is a variant of [Reduce] passing the context of the transformer to the function
+func ReduceCtx[IN0 any](fn func(ctx context.Context, in1, in2 IN0) (IN0, error)) ReducerWrapper {
+	var initValue IN0
+	fold := FoldCtx(initValue, fn)
+	fold.Name = "ReduceCtx"
+	return fold
+}
+
 type number interface {
 	int | int8 | int16 | int32 | int64 |
 		uint | uint8 | uint16 | uint32 | uint64 |
@@ -187,5 +207,10 @@ func Avg() ReducerWrapper {
 		return avg, nil
 	})
 	avgFn.Name = "Avg"
+	resetAvg := avgFn.Reset
+	avgFn.Reset = func() {
+		resetAvg()
+		counter, sum = 0, 0
+	}
 	return avgFn
 }
@@ -1,6 +1,7 @@
 package steps
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -40,6 +41,53 @@ func TestGroupBy_Failure(t *testing.T) {
 	assert.Nil(t, actual)
 }
 
+func TestGroupBy_ErrorPolicy(t *testing.T) {
+	errBad := errors.New("bad input")
+	for _, sc := range []struct {
+		name               string
+		policy             ErrorPolicy
+		expected           map[any][]any
+		expectedDeadLetter []FailedItem
+	}{
+		{
+			name:   "stop",
+			policy: Stop,
+		}, {
+			name:     "continue",
+			policy:   Continue,
+			expected: map[any][]any{"ok": {1, 3}},
+		}, {
+			name:     "dead_letter",
+			policy:   DeadLetter,
+			expected: map[any][]any{"ok": {1, 3}},
+			expectedDeadLetter: []FailedItem{
+				{Error: errBad, Step: "GroupBy", Position: 1, Args: Args{2}, ArgsLen: 1},
+			},
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			var deadLetter []FailedItem
+			actual := Transform[int]([]int{1, 2, 3},
+				WithErrorPolicy(sc.policy),
+				WithErrorHandler(expectsError(t, sc.policy != DeadLetter)),
+				WithDeadLetter(func(item FailedItem) {
+					deadLetter = append(deadLetter, item)
+				})).
+				With(Aggregate(
+					GroupBy(func(in int) (string, int, error) {
+						if in == 2 {
+							return "bad", in, errBad
+						}
+						return "ok", in, nil
+					}))).
+				AsMultiMap()
+
+			assert.Equal(t, sc.expected, actual)
+			assert.Equal(t, sc.expectedDeadLetter, deadLetter)
+		})
+	}
+}
+
 func TestGroupBy_Validate(t *testing.T) {
 	for _, sc := range []struct {
 		name         string
@@ -103,6 +151,39 @@ func TestFold_Failure(t *testing.T) {
 	assert.Empty(t, actual)
 }
 
+func TestFold_ErrorPolicy(t *testing.T) {
+	errBad := errors.New("bad input")
+	for _, sc := range []struct {
+		name     string
+		policy   ErrorPolicy
+		expected []any
+	}{
+		{
+			name:     "stop",
+			policy:   Stop,
+			expected: []any{},
+		}, {
+			name:     "continue",
+			policy:   Continue,
+			expected: []any{-4 + 1 + 3},
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			actual := Transform[int]([]int{1, 2, 3}, WithErrorPolicy(sc.policy), WithErrorHandler(expectsError(t, true))).
+				With(Aggregate(
+					Fold(-4, func(in1, in2 int) (int, error) {
+						if in2 == 2 {
+							return in1 + in2, errBad
+						}
+						return in1 + in2, nil
+					}))).
+				AsSlice()
+
+			assert.Equal(t, sc.expected, actual)
+		})
+	}
+}
+
 func TestFold_Validate(t *testing.T) {
 	for _, sc := range []struct {
 		name         string
@@ -261,6 +342,33 @@ func ExampleGroupBy() {
 	// Output: map[0:[2 4] 1:[1 3 5]]
 }
 
+func ExampleEmitEvery() {
+	res := Transform[int]([]int{1, 2, 3, 4, 5}).
+		With(Aggregate(
+			Sum[int](),
+			EmitEvery(2),
+		)).
+		AsSlice()
+
+	fmt.Println(res)
+	// Output: [3 10 15]
+}
+
+func ExampleResetOnEmit() {
+	res := Transform[int]([]int{1, 2, 3, 4, 5}).
+		With(Aggregate(
+			Sum[int](),
+			EmitWhen(func(in int) bool {
+				return in%2 == 0
+			}),
+			ResetOnEmit(),
+		)).
+		AsSlice()
+
+	fmt.Println(res)
+	// Output: [3 7 5]
+}
+
 func ExampleFold() {
 	res := Transform[int]([]int{1, 2, 3, 4, 5}).
 		With(Aggregate(
@@ -330,3 +438,61 @@ func ExampleAvg() {
 	fmt.Println(res)
 	// Output: [-3.1]
 }
+
+func TestCtxReducers_PassTransformerContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), testCtxKey{}, 10)
+	multiplier := func(ctx context.Context) int {
+		return ctx.Value(testCtxKey{}).(int)
+	}
+
+	for _, sc := range []struct {
+		name       string
+		aggregator ReducerWrapper
+		expected   []any
+	}{
+		{
+			name: "GroupByCtx",
+			aggregator: GroupByCtx(func(ctx context.Context, in int) (bool, int, error) {
+				return in%2 == 0, in * multiplier(ctx), nil
+			}),
+			expected: []any{map[bool][]int{false: {10, 30}, true: {20}}},
+		}, {
+			name: "FoldCtx",
+			aggregator: FoldCtx(1, func(ctx context.Context, in1, in2 int) (int, error) {
+				return in1 + in2*multiplier(ctx), nil
+			}),
+			expected: []any{61},
+		}, {
+			name: "ReduceCtx",
+			aggregator: ReduceCtx(func(ctx context.Context, in1, in2 int) (int, error) {
+				return in1 + in2*multiplier(ctx), nil
+			}),
+			expected: []any{60},
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			assert.Equal(t, sc.name, sc.aggregator.Name)
+			actual := Transform[int]([]int{1, 2, 3}, WithContext(ctx), WithErrorHandler(expectsError(t, false))).
+				With(Aggregate(sc.aggregator)).
+				AsSlice()
+
+			assert.Equal(t, sc.expected, actual)
+		})
+	}
+}
+
+func ExampleFoldCtx() {
+	type offsetKey struct{}
+	ctx := context.WithValue(context.Background(), offsetKey{}, 100)
+
+	res := Transform[int]([]int{1, 2, 3}, WithContext(ctx)).
+		With(Aggregate(
+			FoldCtx(0, func(ctx context.Context, in1, in2 int) (int, error) {
+				return in1 + in2 + ctx.Value(offsetKey{}).(int), nil
+			}),
+		)).
+		AsSlice()
+
+	fmt.Println(res)
+	// Output: [306]
+}
@@ -0,0 +1,406 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy defines how a failed step is retried
+type RetryPolicy struct {
+	MaxAttempts int              // maximum number of attempts including the first one (no retries when less than 2)
+	Backoff     time.Duration    // delay before the first retry
+	MaxBackoff  time.Duration    // upper limit of the delay between attempts (unlimited when 0)
+	Multiplier  float64          // growth factor of the delay after each retry (2 when less than 1)
+	Jitter      float64          // random fraction (between 0 and 1) of the delay subtracted from it
+	Retryable   func(error) bool // decides if an error is retryable (every error is retried when nil)
+}
+
+// maxRetryDelay limits the exponentially growing delay, so it could be converted to a duration without overflow
+const maxRetryDelay = time.Duration(1 << 62)
+
+func (p RetryPolicy) delay(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	delay := float64(p.Backoff) * math.Pow(multiplier, float64(retry))
+	if math.IsNaN(delay) || delay < 0 {
+		delay = 0
+	}
+	delay = min(delay, float64(maxRetryDelay))
+	if p.MaxBackoff > 0 {
+		delay = min(delay, float64(p.MaxBackoff))
+	}
+	if p.Jitter > 0 {
+		delay -= delay * min(p.Jitter, 1) * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// Retry re-invokes the wrapped step while it returns a retryable error, waiting between the attempts as the policy defines.
+// The error of the last attempt is returned when the attempts are exhausted,
+// and the waiting stops when the context of the transformer is cancelled.
+func Retry(step StepWrapper, policy RetryPolicy) StepWrapper {
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = func(error) bool { return true }
+	}
+
+	return StepWrapper{
+		Name: fmt.Sprintf("Retry(%s)", step.Name),
+		StepFn: func(in StepInput) StepOutput {
+			out := step.StepFn(in)
+			for retry := 0; retry+1 < policy.MaxAttempts && out.Error != nil && retryable(out.Error); retry++ {
+				if err := wait(in.TransformerOptions.Ctx, policy.delay(retry)); err != nil {
+					return StepOutput{Error: err}
+				}
+				out = step.StepFn(in)
+			}
+			return out
+		},
+		Validate:    step.Validate,
+		Reset:       step.Reset,
+		Flush:       step.Flush,
+		FlushAt:     step.FlushAt,
+		Ready:       step.Ready,
+		FlushReady:  step.FlushReady,
+		ErrorPolicy: step.ErrorPolicy,
+	}
+}
+
+// WithTimeout runs the wrapped step with a context cancelled after the timeout, and returns [ErrStepTimeout] when
+// the step doesn't finish in time. The step runs on a separate goroutine, which is left behind after the timeout,
+// so the step should stop using the context of the transformer (passed in the [StepInput]).
+// The abandoned step could still be running while the next item is processed, so the validation fails with
+// [ErrInvalidStepConfig] for stateful steps (having Reset or Flush).
+func WithTimeout(step StepWrapper, timeout time.Duration) StepWrapper {
+	return StepWrapper{
+		Name: fmt.Sprintf("WithTimeout(%s)", step.Name),
+		StepFn: func(in StepInput) StepOutput {
+			parent := in.TransformerOptions.Ctx
+			if parent == nil {
+				parent = context.Background()
+			}
+			ctx, cancel := context.WithTimeout(parent, timeout)
+			defer cancel()
+			in.TransformerOptions.Ctx = ctx
+
+			resCh := make(chan StepOutput, 1)
+			go func() {
+				resCh <- recoverStep(step.StepFn, in)
+			}()
+
+			var out StepOutput
+			select {
+			case out = <-resCh:
+				repanic(out.Error)
+				if out.Error == nil || ctx.Err() == nil {
+					return out
+				}
+			case <-ctx.Done():
+			}
+
+			// the step failed because of the cancelled context
+			if err := parent.Err(); err != nil {
+				return StepOutput{Error: err}
+			}
+			return StepOutput{Error: fmt.Errorf("%w [%s:%s]", ErrStepTimeout, step.Name, timeout)}
+		},
+		Validate: func(prevStepOut ArgTypes) (ArgTypes, error) {
+			if step.Reset != nil || step.Flush != nil {
+				return ArgTypes{}, fmt.Errorf("%w [stateful step:%s]", ErrInvalidStepConfig, step.Name)
+			}
+			return step.Validate(prevStepOut)
+		},
+		ErrorPolicy: step.ErrorPolicy,
+	}
+}
+
+// wait blocks for the given duration or until the context is cancelled
+func wait(ctx context.Context, d time.Duration) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// OnError overrides the error policy of the transformer for the given step
+func OnError(step StepWrapper, policy ErrorPolicy) StepWrapper {
+	step.ErrorPolicy = policy
+	return step
+}
+
+// tokenBucket is a token bucket refilled continuously by rate tokens per second up to the burst size
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes a token from the bucket, and returns the time to wait until the token is available
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+	if b.last.IsZero() {
+		b.tokens = b.burst
+	} else {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Throttle limits the rate of the inputs passed to the next steps using a token bucket.
+// The rate is the number of inputs per second (unlimited when not positive),
+// and burst is the number of inputs that could pass without waiting (at least 1).
+// The waiting stops when the context of the transformer is cancelled.
+func Throttle[IN0 any](rate float64, burst int) StepWrapper {
+	bucket := &tokenBucket{rate: rate, burst: float64(max(burst, 1))}
+	return StepWrapper{
+		Name: "Throttle",
+		StepFn: func(in StepInput) StepOutput {
+			if err := wait(in.TransformerOptions.Ctx, bucket.reserve(time.Now())); err != nil {
+				return StepOutput{Error: err}
+			}
+			return StepOutput{
+				Args:    Args{in.Args[0]},
+				ArgsLen: 1,
+			}
+		},
+		Validate: simpleFilterValidation[IN0],
+		Reset: func() {
+			bucket = &tokenBucket{rate: rate, burst: bucket.burst}
+		},
+	}
+}
+
+// ThrottleBy limits the rate of the inputs passed to the next steps using a separate token bucket for each key.
+// The key of an input is returned by keyFn, the rate and burst are applied to each key the same way as in [Throttle].
+func ThrottleBy[IN0 any, K comparable](rate float64, burst int, keyFn func(in IN0) (K, error)) StepWrapper {
+	throttleByFn := ThrottleByCtx(rate, burst, func(_ context.Context, in IN0) (K, error) {
+		return keyFn(in)
+	})
+	throttleByFn.Name = "ThrottleBy"
+	return throttleByFn
+}
+
+// ThrottleByCtx is a variant of [ThrottleBy] passing the context of the transformer to the key function
+func ThrottleByCtx[IN0 any, K comparable](rate float64, burst int, keyFn func(ctx context.Context, in IN0) (K, error)) StepWrapper {
+	buckets := map[K]*tokenBucket{}
+	return StepWrapper{
+		Name: "ThrottleByCtx",
+		StepFn: func(in StepInput) StepOutput {
+			key, err := keyFn(ctxOf(in), in.Args[0].(IN0))
+			if err != nil {
+				return StepOutput{Error: err}
+			}
+
+			bucket, ok := buckets[key]
+			if !ok {
+				bucket = &tokenBucket{rate: rate, burst: float64(max(burst, 1))}
+				buckets[key] = bucket
+			}
+			if err := wait(in.TransformerOptions.Ctx, bucket.reserve(time.Now())); err != nil {
+				return StepOutput{Error: err}
+			}
+			return StepOutput{
+				Args:    Args{in.Args[0]},
+				ArgsLen: 1,
+			}
+		},
+		Validate: simpleFilterValidation[IN0],
+		Reset: func() {
+			buckets = map[K]*tokenBucket{}
+		},
+	}
+}
+
+// BreakerState is the state of a circuit breaker
+type BreakerState uint8
+
+const (
+	BreakerClosed   BreakerState = iota // the wrapped step is called
+	BreakerOpen                         // the wrapped step is not called, the breaker fails fast or calls the fallback step
+	BreakerHalfOpen                     // the next call is a trial deciding whether the breaker closes or opens again
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", s)
+	}
+}
+
+// BreakerConfig defines when a circuit breaker opens and closes
+type BreakerConfig struct {
+	ConsecutiveFailures int              // opens after the given number of consecutive failures (disabled when 0)
+	FailureRate         float64          // opens when the rate of failures (between 0 and 1) reaches it in the rolling window (disabled when 0)
+	Window              int              // number of the last calls used for the failure rate
+	CoolDown            time.Duration    // time spent in open state before a trial call is allowed
+	Fallback            *StepWrapper     // step called instead of the failed or skipped calls (fails fast with ErrCircuitOpen when nil)
+	IsFailure           func(error) bool // decides if an error counts as a failure (every error counts when nil)
+}
+
+// CircuitBreaker tracks the failures of the wrapped step, and stops calling it once the failures reach the configured limit.
+// While the breaker is open the inputs are failing fast with [ErrCircuitOpen] or they are passed to the fallback step.
+// After the cool-down the next input is a trial: the breaker closes when it succeeds or opens again when it fails.
+// The state changes are written to the log writer of the transformer.
+func CircuitBreaker(step StepWrapper, cfg BreakerConfig) StepWrapper {
+	name := fmt.Sprintf("CircuitBreaker(%s)", step.Name)
+	isFailure := cfg.IsFailure
+	if isFailure == nil {
+		isFailure = func(error) bool { return true }
+	}
+
+	var (
+		state       BreakerState
+		openedAt    time.Time
+		consecutive int
+		window      []bool
+		windowPos   int
+		failures    int
+	)
+	reset := func() {
+		state, openedAt = BreakerClosed, time.Time{}
+		consecutive, windowPos, failures = 0, 0, 0
+		window = make([]bool, 0, max(cfg.Window, 0))
+	}
+	reset()
+
+	setState := func(opts TransformerOptions, newState BreakerState) {
+		if opts.LogWriter != nil {
+			transformerName := ""
+			if opts.Name != "" {
+				transformerName = "transformer:" + opts.Name + " "
+			}
+			fmt.Fprintf(opts.LogWriter, "%s %s%s -> %s\n", name, transformerName, state, newState)
+		}
+		if newState == BreakerOpen {
+			openedAt = time.Now()
+		}
+		state = newState
+		consecutive, windowPos, failures = 0, 0, 0
+		window = window[:0]
+	}
+	// record registers the result of a call, and returns true when the breaker must open
+	record := func(failed bool) bool {
+		consecutive++
+		if !failed {
+			consecutive = 0
+		}
+		if cfg.FailureRate > 0 && cfg.Window > 0 {
+			if len(window) < cfg.Window {
+				window = append(window, failed)
+			} else {
+				if window[windowPos] {
+					failures--
+				}
+				window[windowPos] = failed
+				windowPos = (windowPos + 1) % cfg.Window
+			}
+			if failed {
+				failures++
+			}
+		}
+
+		switch {
+		case cfg.ConsecutiveFailures > 0 && consecutive >= cfg.ConsecutiveFailures:
+			return true
+		case len(window) == cfg.Window && cfg.Window > 0 && cfg.FailureRate > 0:
+			return float64(failures)/float64(cfg.Window) >= cfg.FailureRate
+		default:
+			return false
+		}
+	}
+	fallback := func(in StepInput) StepOutput {
+		if cfg.Fallback == nil {
+			return StepOutput{Error: fmt.Errorf("%w [%s]", ErrCircuitOpen, step.Name)}
+		}
+		return cfg.Fallback.StepFn(in)
+	}
+
+	return StepWrapper{
+		Name: name,
+		StepFn: func(in StepInput) StepOutput {
+			if state == BreakerOpen {
+				if time.Since(openedAt) < cfg.CoolDown {
+					return fallback(in)
+				}
+				setState(in.TransformerOptions, BreakerHalfOpen)
+			}
+
+			out := step.StepFn(in)
+			failed := out.Error != nil && isFailure(out.Error)
+			switch {
+			case state == BreakerHalfOpen && failed:
+				setState(in.TransformerOptions, BreakerOpen)
+			case state == BreakerHalfOpen:
+				setState(in.TransformerOptions, BreakerClosed)
+			case record(failed):
+				setState(in.TransformerOptions, BreakerOpen)
+			}
+
+			if failed && cfg.Fallback != nil {
+				return cfg.Fallback.StepFn(in)
+			}
+			return out
+		},
+		Validate: func(prevStepOut ArgTypes) (ArgTypes, error) {
+			outTypes, err := step.Validate(prevStepOut)
+			if err != nil || cfg.Fallback == nil {
+				return outTypes, err
+			}
+			fallbackOutTypes, err := cfg.Fallback.Validate(prevStepOut)
+			if err != nil {
+				return ArgTypes{}, fmt.Errorf("%w [%s]: %w", ErrInvalidFallback, cfg.Fallback.Name, err)
+			}
+			for i := range maxArgs {
+				if fallbackOutTypes[i] != outTypes[i] {
+					return ArgTypes{}, fmt.Errorf("%w [%s]: output argument types differ [%v!=%v:%d]", ErrInvalidFallback, cfg.Fallback.Name, fallbackOutTypes[i], outTypes[i], i+1)
+				}
+			}
+			return outTypes, nil
+		},
+		Reset: func() {
+			reset()
+			if step.Reset != nil {
+				step.Reset()
+			}
+			if cfg.Fallback != nil && cfg.Fallback.Reset != nil {
+				cfg.Fallback.Reset()
+			}
+		},
+		Flush:       step.Flush,
+		FlushAt:     step.FlushAt,
+		Ready:       step.Ready,
+		FlushReady:  step.FlushReady,
+		ErrorPolicy: step.ErrorPolicy,
+	}
+}
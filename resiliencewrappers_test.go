@@ -0,0 +1,696 @@
+package steps
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errFlaky = errors.New("flaky error")
+
+// flakyMap fails the given number of times for each input before succeeding
+func flakyMap(failures int, calls map[string]int) StepWrapper {
+	return Map(func(in string) (int, error) {
+		calls[in]++
+		if calls[in] <= failures {
+			return 0, fmt.Errorf("%w: %s", errFlaky, in)
+		}
+		return strconv.Atoi(in)
+	})
+}
+
+func TestRetry_Success(t *testing.T) {
+	calls := map[string]int{}
+	actual := Transform[string]([]string{"1", "2"}, WithErrorHandler(expectsError(t, false))).
+		WithSteps(
+			Retry(flakyMap(2, calls), RetryPolicy{
+				MaxAttempts: 3,
+				Backoff:     time.Millisecond,
+				Jitter:      0.5,
+			}),
+		).
+		AsSlice()
+
+	assert.Equal(t, []any{1, 2}, actual)
+	assert.Equal(t, map[string]int{"1": 3, "2": 3}, calls)
+}
+
+func TestRetry_Failure(t *testing.T) {
+	for _, sc := range []struct {
+		name          string
+		policy        RetryPolicy
+		expectedCalls int
+		expectedErr   error
+	}{
+		{
+			name:          "attempts_exhausted",
+			policy:        RetryPolicy{MaxAttempts: 3},
+			expectedCalls: 3,
+			expectedErr:   errFlaky,
+		}, {
+			name: "error_not_retryable",
+			policy: RetryPolicy{
+				MaxAttempts: 3,
+				Retryable: func(err error) bool {
+					return !errors.Is(err, errFlaky)
+				},
+			},
+			expectedCalls: 1,
+			expectedErr:   errFlaky,
+		}, {
+			name:          "context_cancelled_while_waiting",
+			policy:        RetryPolicy{MaxAttempts: 3, Backoff: time.Hour},
+			expectedCalls: 1,
+			expectedErr:   context.Canceled,
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			time.AfterFunc(10*time.Millisecond, cancel)
+
+			var actualErr error
+			calls := map[string]int{}
+			actual := Transform[string]([]string{"1"}, WithContext(ctx), WithErrorHandler(func(err error) {
+				actualErr = err
+			})).
+				WithSteps(
+					Retry(flakyMap(5, calls), sc.policy),
+				).
+				AsSlice()
+
+			assert.Empty(t, actual)
+			assert.Equal(t, sc.expectedCalls, calls["1"])
+			assert.ErrorIs(t, actualErr, sc.expectedErr)
+		})
+	}
+}
+
+func TestRetry_Validate(t *testing.T) {
+	step := Retry(Map(func(in string) (int, error) {
+		return 0, nil
+	}), RetryPolicy{})
+
+	actualOut, actualErr := step.Validate(ArgTypes{reflect.TypeFor[string]()})
+	assert.Equal(t, ArgTypes{reflect.TypeFor[int]()}, actualOut)
+	assert.NoError(t, actualErr)
+
+	_, actualErr = step.Validate(ArgTypes{reflect.TypeFor[int]()})
+	assert.ErrorIs(t, actualErr, ErrIncompatibleInArgType)
+	assert.Equal(t, "Retry(Map)", step.Name)
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{Backoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	assert.Equal(t, 10*time.Millisecond, policy.delay(0))
+	assert.Equal(t, 20*time.Millisecond, policy.delay(1))
+	assert.Equal(t, 40*time.Millisecond, policy.delay(2))
+	assert.Equal(t, 50*time.Millisecond, policy.delay(3))
+
+	policy.Multiplier = 3
+	policy.Jitter = 0.5
+	for range 10 {
+		delay := policy.delay(1)
+		assert.LessOrEqual(t, delay, 30*time.Millisecond)
+		assert.GreaterOrEqual(t, delay, 15*time.Millisecond)
+	}
+}
+
+func TestRetryPolicy_DelayDoesNotOverflow(t *testing.T) {
+	policy := RetryPolicy{Backoff: time.Second, Multiplier: 10}
+	assert.Equal(t, maxRetryDelay, policy.delay(5000))
+
+	policy.MaxBackoff = time.Minute
+	assert.Equal(t, time.Minute, policy.delay(5000))
+
+	policy = RetryPolicy{Multiplier: 10}
+	assert.Equal(t, time.Duration(0), policy.delay(5000))
+}
+
+func TestOnError(t *testing.T) {
+	deadLetterCh := make(chan FailedItem, 5)
+	var reportedErrs []error
+	actual := Transform[string]([]string{"1", "x", "2", "y"},
+		WithErrorPolicy(DeadLetter),
+		WithDeadLetterChan(deadLetterCh),
+		WithErrorHandler(func(err error) {
+			reportedErrs = append(reportedErrs, err)
+		}),
+	).
+		WithSteps(
+			Map(func(in string) (int, error) {
+				return strconv.Atoi(in)
+			}),
+			OnError(Filter(func(in int) (bool, error) {
+				if in > 1 {
+					return false, errors.New("too big")
+				}
+				return true, nil
+			}), Continue),
+		).
+		AsSlice()
+	close(deadLetterCh)
+
+	assert.Equal(t, []any{1}, actual)
+	assert.Equal(t, []error{errors.New("too big")}, reportedErrs)
+	failedInputs := []any{}
+	for item := range deadLetterCh {
+		assert.Equal(t, "Map", item.Step)
+		assert.Equal(t, 1, item.Position)
+		failedInputs = append(failedInputs, item.Args[0])
+	}
+	assert.Equal(t, []any{"x", "y"}, failedInputs)
+}
+
+func TestTokenBucket_Reserve(t *testing.T) {
+	now := time.Now()
+	bucket := &tokenBucket{rate: 10, burst: 2}
+
+	assert.Equal(t, time.Duration(0), bucket.reserve(now))
+	assert.Equal(t, time.Duration(0), bucket.reserve(now))
+	assert.Equal(t, 100*time.Millisecond, bucket.reserve(now))
+	assert.Equal(t, 150*time.Millisecond, bucket.reserve(now.Add(50*time.Millisecond)))
+	assert.Equal(t, time.Duration(0), bucket.reserve(now.Add(time.Second)))
+
+	unlimited := &tokenBucket{burst: 1}
+	for range 3 {
+		assert.Equal(t, time.Duration(0), unlimited.reserve(now))
+	}
+}
+
+func TestThrottle_Success(t *testing.T) {
+	start := time.Now()
+	actual := Transform[int]([]int{1, 2, 3, 4}, WithErrorHandler(expectsError(t, false))).
+		WithSteps(
+			Throttle[int](100, 2),
+		).
+		AsSlice()
+
+	assert.Equal(t, []any{1, 2, 3, 4}, actual)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestThrottle_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	var actualErr error
+	actual := Transform[int]([]int{1, 2, 3}, WithContext(ctx), WithErrorHandler(func(err error) {
+		actualErr = err
+	})).
+		WithSteps(
+			Throttle[int](0.1, 1),
+		).
+		AsSlice()
+
+	assert.Equal(t, []any{1}, actual)
+	assert.ErrorIs(t, actualErr, context.Canceled)
+}
+
+func TestThrottleBy_Success(t *testing.T) {
+	start := time.Now()
+	actual := Transform[string]([]string{"a1", "b1", "c1", "a2"}, WithErrorHandler(expectsError(t, false))).
+		WithSteps(
+			ThrottleBy(20, 1, func(in string) (byte, error) {
+				return in[0], nil
+			}),
+		).
+		AsSlice()
+
+	assert.Equal(t, []any{"a1", "b1", "c1", "a2"}, actual)
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestThrottleBy_Failure(t *testing.T) {
+	actual := Transform[string]([]string{"a1", "", "a2"}, WithErrorHandler(expectsError(t, true))).
+		WithSteps(
+			ThrottleBy(1000, 1, func(in string) (byte, error) {
+				if len(in) == 0 {
+					return 0, errors.New("missing key")
+				}
+				return in[0], nil
+			}),
+		).
+		AsSlice()
+
+	assert.Equal(t, []any{"a1"}, actual)
+}
+
+func TestThrottle_Validate(t *testing.T) {
+	for _, stepWrapper := range []StepWrapper{
+		Throttle[string](1, 1),
+		ThrottleBy(1, 1, func(in string) (string, error) {
+			return in, nil
+		}),
+	} {
+		t.Run(stepWrapper.Name, func(t *testing.T) {
+			actualOut, actualErr := stepWrapper.Validate(ArgTypes{reflect.TypeFor[string]()})
+			assert.Equal(t, ArgTypes{reflect.TypeFor[string]()}, actualOut)
+			assert.NoError(t, actualErr)
+
+			_, actualErr = stepWrapper.Validate(ArgTypes{reflect.TypeFor[int]()})
+			assert.ErrorIs(t, actualErr, ErrIncompatibleInArgType)
+			assert.ErrorContains(t, actualErr, "[int!=string:1]")
+		})
+	}
+}
+
+func TestWithTimeout_Success(t *testing.T) {
+	actual := Transform[string]([]string{"1", "2"}, WithErrorHandler(expectsError(t, false))).
+		WithSteps(
+			WithTimeout(Map(func(in string) (int, error) {
+				return strconv.Atoi(in)
+			}), time.Second),
+		).
+		AsSlice()
+
+	assert.Equal(t, []any{1, 2}, actual)
+}
+
+func TestWithTimeout_Failure(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	hangingStep := func(in StepInput) StepOutput {
+		if in.Args[0] == 2 {
+			<-release
+		}
+		return StepOutput{Args: in.Args, ArgsLen: 1}
+	}
+
+	for _, sc := range []struct {
+		name        string
+		step        StepWrapper
+		expected    []any
+		expectedErr string
+	}{
+		{
+			name:        "step_returns_error",
+			step:        Map(func(in int) (int, error) { return 0, errors.New("failed") }),
+			expected:    []any{},
+			expectedErr: "failed",
+		}, {
+			name:        "step_hangs",
+			step:        StepWrapper{Name: "hanging", StepFn: hangingStep, Validate: simpleFilterValidation[int]},
+			expected:    []any{1},
+			expectedErr: "step timed out [hanging:10ms]",
+		}, {
+			name: "step_respects_context",
+			step: StepWrapper{
+				Name: "contextAware",
+				StepFn: func(in StepInput) StepOutput {
+					<-in.TransformerOptions.Ctx.Done()
+					return StepOutput{Error: in.TransformerOptions.Ctx.Err()}
+				},
+				Validate: simpleFilterValidation[int],
+			},
+			expected:    []any{},
+			expectedErr: "step timed out [contextAware:10ms]",
+		}, {
+			name:        "step_panics",
+			step:        Map(func(in int) (int, error) { panic("unexpected") }),
+			expected:    []any{},
+			expectedErr: "step panicked [WithTimeout(Map):1]: unexpected",
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			var actualErr error
+			actual := Transform[int]([]int{1, 2, 3}, WithErrorHandler(func(err error) {
+				actualErr = err
+			})).
+				WithSteps(
+					WithTimeout(sc.step, 10*time.Millisecond),
+				).
+				AsSlice()
+
+			assert.Equal(t, sc.expected, actual)
+			assert.ErrorContains(t, actualErr, sc.expectedErr)
+		})
+	}
+}
+
+func TestWithTimeout_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	var actualErr error
+	Transform[int]([]int{1}, WithContext(ctx), WithErrorHandler(func(err error) {
+		actualErr = err
+	})).
+		WithSteps(
+			WithTimeout(Map(func(in int) (int, error) {
+				time.Sleep(time.Second)
+				return in, nil
+			}), time.Hour),
+		).
+		AsSlice()
+
+	assert.ErrorIs(t, actualErr, context.Canceled)
+	assert.NotErrorIs(t, actualErr, ErrStepTimeout)
+}
+
+func TestWithTimeout_Validate(t *testing.T) {
+	step := WithTimeout(Map(func(in string) (int, error) {
+		return strconv.Atoi(in)
+	}), time.Second)
+	actualOut, actualErr := step.Validate(ArgTypes{reflect.TypeFor[string]()})
+	assert.Equal(t, ArgTypes{reflect.TypeFor[int]()}, actualOut)
+	assert.NoError(t, actualErr)
+
+	_, actualErr = step.Validate(ArgTypes{reflect.TypeFor[int]()})
+	assert.ErrorIs(t, actualErr, ErrIncompatibleInArgType)
+
+	_, actualErr = WithTimeout(Take[int](2), time.Second).Validate(ArgTypes{reflect.TypeFor[int]()})
+	assert.ErrorIs(t, actualErr, ErrInvalidStepConfig)
+	assert.EqualError(t, actualErr, "invalid step configuration [stateful step:Take]")
+
+	_, actualErr = WithTimeout(Batch[int](2, 0), time.Second).Validate(ArgTypes{reflect.TypeFor[int]()})
+	assert.ErrorIs(t, actualErr, ErrInvalidStepConfig)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	atoi := Map(func(in string) (int, error) {
+		return strconv.Atoi(in)
+	})
+	fallback := Map(func(in string) (int, error) {
+		return -1, nil
+	})
+
+	type call struct {
+		in            string
+		sleep         time.Duration
+		expected      any
+		expectedErr   error
+		expectedState BreakerState
+	}
+	for _, sc := range []struct {
+		name        string
+		cfg         BreakerConfig
+		calls       []call
+		expectedLog string
+	}{
+		{
+			name: "opens_after_consecutive_failures",
+			cfg:  BreakerConfig{ConsecutiveFailures: 2, CoolDown: time.Hour},
+			calls: []call{
+				{in: "x", expectedErr: strconv.ErrSyntax, expectedState: BreakerClosed},
+				{in: "1", expected: 1, expectedState: BreakerClosed},
+				{in: "x", expectedErr: strconv.ErrSyntax, expectedState: BreakerClosed},
+				{in: "x", expectedErr: strconv.ErrSyntax, expectedState: BreakerOpen},
+				{in: "2", expectedErr: ErrCircuitOpen, expectedState: BreakerOpen},
+			},
+			expectedLog: "CircuitBreaker(Map) transformer:test closed -> open\n",
+		}, {
+			name: "opens_on_failure_rate",
+			cfg:  BreakerConfig{FailureRate: 0.5, Window: 4, CoolDown: time.Hour},
+			calls: []call{
+				{in: "x", expectedErr: strconv.ErrSyntax, expectedState: BreakerClosed},
+				{in: "1", expected: 1, expectedState: BreakerClosed},
+				{in: "2", expected: 2, expectedState: BreakerClosed},
+				{in: "3", expected: 3, expectedState: BreakerClosed},
+				{in: "x", expectedErr: strconv.ErrSyntax, expectedState: BreakerClosed},
+				{in: "x", expectedErr: strconv.ErrSyntax, expectedState: BreakerOpen},
+				{in: "4", expectedErr: ErrCircuitOpen, expectedState: BreakerOpen},
+			},
+			expectedLog: "CircuitBreaker(Map) transformer:test closed -> open\n",
+		}, {
+			name: "closes_after_successful_trial",
+			cfg:  BreakerConfig{ConsecutiveFailures: 1, CoolDown: 10 * time.Millisecond},
+			calls: []call{
+				{in: "x", expectedErr: strconv.ErrSyntax, expectedState: BreakerOpen},
+				{in: "1", expectedErr: ErrCircuitOpen, expectedState: BreakerOpen},
+				{in: "2", sleep: 20 * time.Millisecond, expected: 2, expectedState: BreakerClosed},
+			},
+			expectedLog: "CircuitBreaker(Map) transformer:test closed -> open\n" +
+				"CircuitBreaker(Map) transformer:test open -> half-open\n" +
+				"CircuitBreaker(Map) transformer:test half-open -> closed\n",
+		}, {
+			name: "opens_after_failed_trial",
+			cfg:  BreakerConfig{ConsecutiveFailures: 1, CoolDown: 10 * time.Millisecond},
+			calls: []call{
+				{in: "x", expectedErr: strconv.ErrSyntax, expectedState: BreakerOpen},
+				{in: "x", sleep: 20 * time.Millisecond, expectedErr: strconv.ErrSyntax, expectedState: BreakerOpen},
+				{in: "1", expectedErr: ErrCircuitOpen, expectedState: BreakerOpen},
+			},
+			expectedLog: "CircuitBreaker(Map) transformer:test closed -> open\n" +
+				"CircuitBreaker(Map) transformer:test open -> half-open\n" +
+				"CircuitBreaker(Map) transformer:test half-open -> open\n",
+		}, {
+			name: "routes_to_fallback",
+			cfg:  BreakerConfig{ConsecutiveFailures: 1, CoolDown: time.Hour, Fallback: &fallback},
+			calls: []call{
+				{in: "1", expected: 1, expectedState: BreakerClosed},
+				{in: "x", expected: -1, expectedState: BreakerOpen},
+				{in: "2", expected: -1, expectedState: BreakerOpen},
+			},
+			expectedLog: "CircuitBreaker(Map) transformer:test closed -> open\n",
+		}, {
+			name: "ignores_errors_not_counted_as_failure",
+			cfg: BreakerConfig{ConsecutiveFailures: 1, CoolDown: time.Hour, IsFailure: func(err error) bool {
+				return !errors.Is(err, strconv.ErrSyntax)
+			}},
+			calls: []call{
+				{in: "x", expectedErr: strconv.ErrSyntax, expectedState: BreakerClosed},
+				{in: "1", expected: 1, expectedState: BreakerClosed},
+			},
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			log := bytes.NewBufferString("")
+			opts := TransformerOptions{Name: "test", LogWriter: log, Ctx: context.Background()}
+			breaker := CircuitBreaker(atoi, sc.cfg)
+			breaker.Reset()
+			state := func() BreakerState {
+				lines := strings.Split(strings.TrimSpace(log.String()), " -> ")
+				switch lines[len(lines)-1] {
+				case "open":
+					return BreakerOpen
+				case "half-open":
+					return BreakerHalfOpen
+				default:
+					return BreakerClosed
+				}
+			}
+
+			for i, c := range sc.calls {
+				time.Sleep(c.sleep)
+				out := breaker.StepFn(StepInput{Args: Args{c.in}, ArgsLen: 1, TransformerOptions: opts})
+				if c.expectedErr != nil {
+					assert.ErrorIs(t, out.Error, c.expectedErr, "call %d", i+1)
+				} else {
+					assert.NoError(t, out.Error, "call %d", i+1)
+					assert.Equal(t, c.expected, out.Args[0], "call %d", i+1)
+				}
+				assert.Equal(t, c.expectedState, state(), "call %d", i+1)
+			}
+			assert.Equal(t, sc.expectedLog, log.String())
+		})
+	}
+}
+
+func TestCircuitBreaker_Reset(t *testing.T) {
+	calls := map[string]int{}
+	breaker := CircuitBreaker(flakyMap(5, calls), BreakerConfig{ConsecutiveFailures: 1, CoolDown: time.Hour})
+	transformer := Transform[string]([]string{"1", "1"}, WithErrorPolicy(Continue), WithLogWriter(io.Discard)).
+		WithSteps(breaker)
+
+	for range 2 {
+		transformer.AsSlice()
+	}
+	assert.Equal(t, 2, calls["1"])
+}
+
+func TestCircuitBreaker_Validate(t *testing.T) {
+	atoi := Map(func(in string) (int, error) {
+		return strconv.Atoi(in)
+	})
+	intFallback := Map(func(in string) (int, error) {
+		return 0, nil
+	})
+	stringFallback := Map(func(in string) (string, error) {
+		return in, nil
+	})
+	incompatibleFallback := Map(func(in int) (int, error) {
+		return in, nil
+	})
+
+	for _, sc := range []struct {
+		name        string
+		fallback    *StepWrapper
+		prevStepOut ArgTypes
+		expectedOut ArgTypes
+		expectedErr error
+	}{
+		{
+			name:        "matching_prev_step_out_type",
+			prevStepOut: ArgTypes{reflect.TypeFor[string]()},
+			expectedOut: ArgTypes{reflect.TypeFor[int]()},
+		}, {
+			name:        "different_prev_step_out_type",
+			prevStepOut: ArgTypes{reflect.TypeFor[int]()},
+			expectedErr: ErrIncompatibleInArgType,
+		}, {
+			name:        "matching_fallback",
+			fallback:    &intFallback,
+			prevStepOut: ArgTypes{reflect.TypeFor[string]()},
+			expectedOut: ArgTypes{reflect.TypeFor[int]()},
+		}, {
+			name:        "fallback_with_different_output",
+			fallback:    &stringFallback,
+			prevStepOut: ArgTypes{reflect.TypeFor[string]()},
+			expectedErr: ErrInvalidFallback,
+		}, {
+			name:        "fallback_with_different_input",
+			fallback:    &incompatibleFallback,
+			prevStepOut: ArgTypes{reflect.TypeFor[string]()},
+			expectedErr: ErrIncompatibleInArgType,
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			actualOut, actualErr := CircuitBreaker(atoi, BreakerConfig{Fallback: sc.fallback}).Validate(sc.prevStepOut)
+
+			assert.Equal(t, sc.expectedOut, actualOut)
+			if sc.expectedErr != nil {
+				assert.ErrorIs(t, actualErr, sc.expectedErr)
+			} else {
+				assert.NoError(t, actualErr)
+			}
+		})
+	}
+}
+
+func ExampleRetry() {
+	attempts := 0
+	res := Transform[string]([]string{"1", "2"}).
+		WithSteps(
+			Retry(Map(func(in string) (int, error) {
+				attempts++
+				if attempts%2 == 1 {
+					return 0, errors.New("temporary error")
+				}
+				return strconv.Atoi(in)
+			}), RetryPolicy{
+				MaxAttempts: 3,
+				Backoff:     time.Millisecond,
+			}),
+		).
+		AsSlice()
+
+	fmt.Println(res, attempts)
+	// Output: [1 2] 4
+}
+
+func ExampleWithErrorPolicy() {
+	res := Transform[string]([]string{"1", "x", "2"},
+		WithErrorPolicy(DeadLetter),
+		WithDeadLetterWriter(os.Stdout),
+	).
+		WithSteps(
+			Map(func(in string) (int, error) {
+				return strconv.Atoi(in)
+			}),
+		).
+		AsSlice()
+
+	fmt.Println(res)
+	// Output:
+	// [Map:1] strconv.Atoi: parsing "x": invalid syntax 	arg0: x
+	// [1 2]
+}
+
+func ExampleOnError() {
+	res := Transform[string]([]string{"1", "x", "2"}, WithLogWriter(os.Stdout)).
+		WithSteps(
+			OnError(Map(func(in string) (int, error) {
+				return strconv.Atoi(in)
+			}), Continue),
+		).
+		AsSlice()
+
+	fmt.Println(res)
+	// Output:
+	// error occured: strconv.Atoi: parsing "x": invalid syntax
+	// [1 2]
+}
+
+func ExampleThrottle() {
+	res := Transform[int]([]int{1, 2, 3}).
+		WithSteps(
+			Throttle[int](1000, 1),
+		).
+		AsSlice()
+
+	fmt.Println(res)
+	// Output: [1 2 3]
+}
+
+func ExampleThrottleBy() {
+	type request struct {
+		Host string
+		Path string
+	}
+	res := Transform[request]([]request{{"a", "/1"}, {"b", "/1"}, {"a", "/2"}}).
+		WithSteps(
+			ThrottleBy(1000, 1, func(in request) (string, error) {
+				return in.Host, nil
+			}),
+			Map(func(in request) (string, error) {
+				return in.Host + in.Path, nil
+			}),
+		).
+		AsSlice()
+
+	fmt.Println(res)
+	// Output: [a/1 b/1 a/2]
+}
+
+func ExampleWithTimeout() {
+	var transformErr error
+	res := Transform[int]([]int{1, 2, 3}, WithErrorHandler(func(err error) {
+		transformErr = err
+	})).
+		WithSteps(
+			WithTimeout(Map(func(in int) (int, error) {
+				time.Sleep(time.Duration(in*in) * 10 * time.Millisecond)
+				return in, nil
+			}), 50*time.Millisecond),
+		).
+		AsSlice()
+
+	fmt.Println(res)
+	fmt.Println(transformErr)
+	// Output:
+	// [1 2]
+	// step timed out [Map:50ms]
+}
+
+func ExampleCircuitBreaker() {
+	fallback := Map(func(in string) (int, error) {
+		return 0, nil
+	})
+	res := Transform[string]([]string{"1", "x", "y", "4"}, WithLogWriter(os.Stdout)).
+		WithSteps(
+			CircuitBreaker(Map(func(in string) (int, error) {
+				return strconv.Atoi(in)
+			}), BreakerConfig{
+				ConsecutiveFailures: 2,
+				CoolDown:            time.Minute,
+				Fallback:            &fallback,
+			}),
+		).
+		AsSlice()
+
+	fmt.Println(res)
+	// Output:
+	// CircuitBreaker(Map) closed -> open
+	// [1 0 0 0]
+}
@@ -7,7 +7,9 @@ import (
 	"context"
 	"errors"
 	"io"
+	"iter"
 	"reflect"
+	"time"
 )
 
 const maxArgs = 4
@@ -36,18 +38,29 @@ type (
 
 	// StepOutput holds the output arguments for a single step
 	StepOutput struct {
-		Error   error // error result of the step
-		Args    Args  // output arguments
-		ArgsLen uint8 // length of output arguments (Args can hold zero values, so we need to know it's length)
-		Skip    bool  // used to notify the processor that further transformation steps should be skipped
+		Error       error          // error result of the step (the outputs of the step are discarded)
+		Args        Args           // output arguments
+		ArgsLen     uint8          // length of output arguments (Args can hold zero values, so we need to know it's length)
+		Skip        bool           // used to notify the processor that further transformation steps should be skipped
+		MultiArgs   []Args         // multiple output arguments passed one by one to the next steps (Args and Skip are ignored when not nil)
+		MultiErrors []error        // errors of the multiple outputs by index (nil for the succeeded outputs), the failed outputs are holding the input arguments of the failed items
+		MultiSeq    iter.Seq[Args] // multiple output arguments pulled one by one by the next steps, until the processing is stopped (Args, Skip and MultiArgs are ignored when not nil)
+		Done        bool           // the step won't pass more outputs, so the processing of the input is stopped after this output (the steps are still flushed)
 	}
 
 	// StepWrapper is a container for a single transformation step
 	StepWrapper struct {
-		Name     string                                            // name of the step
-		StepFn   StepFn                                            // the transformation step function
-		Validate func(prevStepArgTypes ArgTypes) (ArgTypes, error) // validation of the current step in the chain
-		Reset    func()                                            // reset the step state before processing
+		Name        string                                            // name of the step
+		StepFn      StepFn                                            // the transformation step function
+		Validate    func(prevStepArgTypes ArgTypes) (ArgTypes, error) // validation of the current step in the chain
+		Reset       func()                                            // reset the step state before processing
+		Flush       func(TransformerOptions) StepOutput               // emit the buffered outputs of the step when the input is exhausted
+		FlushAt     func() time.Time                                  // time when the buffered outputs are flushed before the input is exhausted (zero when nothing is waiting)
+		Ready       func() <-chan struct{}                            // channel closed when the oldest buffered output is ready to be emitted before the input is exhausted (nil when nothing is waiting)
+		FlushReady  func(TransformerOptions) StepOutput               // emit the buffered outputs which are ready, without waiting for the others (see Ready)
+		ErrorPolicy ErrorPolicy                                       // error policy of the step (the transformer error policy is used by default)
+		kernel      stepKernel                                        // typed function of the stateless steps used to fuse them with the neighbouring steps
+		kernelStep  uintptr                                           // code pointer of the StepFn built with the kernel (the step is not fused when it's StepFn is overridden)
 	}
 
 	// ReducerWrapper is a container for an aggregation step
@@ -60,11 +73,27 @@ type (
 
 	// StepsBranch represents a sub-path of a branching transformation chain
 	StepsBranch struct {
-		Error             error           // error result of the sub-path
-		StepWrappers      []StepWrapper   // the steps in the sub-path
-		Steps             []StepFn        // the already validated steps
-		AggregatorWrapper *ReducerWrapper // the aggregation step in the sub-path
-		Aggregator        ReducerFn       // the already validated aggregator function
+		Error             error             // error result of the sub-path
+		StepWrappers      []StepWrapper     // the steps in the sub-path
+		Steps             []StepFn          // the already validated steps
+		AggregatorWrapper *ReducerWrapper   // the aggregation step in the sub-path
+		Aggregator        ReducerFn         // the already validated aggregator function
+		AggregatorOptions AggregatorOptions // the emission policy of the aggregator
+		outTypes          ArgTypes          // the validated output types of the sub-path (unknown types are nil)
+		validatedIn       ArgTypes          // the input types of the successful validation (the validation is not repeated for them)
+		fusions           *stepFusions      // the fused consecutive stateless steps (built when the sub-path is added to a transformer first)
+	}
+
+	// AggregatorOptions holds the emission policy of the aggregator.
+	// By default the aggregated value is emitted only once, when the input is exhausted.
+	// The policies are checked when an input is aggregated, so they are also usable for never ending streaming inputs.
+	// The duration is also checked on time while a channel input is waiting for the next item.
+	AggregatorOptions struct {
+		EveryItems    uint64         // emit the aggregated value after every N aggregated inputs
+		EveryDuration time.Duration  // emit the aggregated value when the duration passed since the last emission
+		Trigger       func(any) bool // emit the aggregated value when the trigger returns true for the aggregated input
+		ResetOnEmit   bool           // reset the aggregator state after each emission
+		triggerType   reflect.Type   // input type of the trigger validated against the aggregator input
 	}
 
 	// SkipFirstArgValidation is used to tell the validator this step is the first in the chain,
@@ -73,13 +102,96 @@ type (
 
 	// TransformerOptions holds the options for the transformer
 	TransformerOptions struct {
-		Name         string
-		LogWriter    io.Writer
-		ErrorHandler func(error)
-		PanicHandler func(error)
-		Ctx          context.Context
-		ChanSize     uint
+		Name              string
+		LogWriter         io.Writer
+		ErrorHandler      func(error)
+		PanicHandler      func(error)
+		Ctx               context.Context
+		ChanSize          uint
+		ErrorPolicy       ErrorPolicy
+		DeadLetter        func(ctx context.Context, item FailedItem) error
+		NoFusion          bool
+		DecodeErrorPolicy DecodeErrorPolicy
+	}
+
+	// DecodeError holds an item which can't be decoded by an input, and it matches [ErrDecodeFailed]
+	DecodeError struct {
+		Source string // name of the input source (like the file path)
+		Line   int    // line of the error (starting from 1)
+		Column int    // column of the error in the line, or the column of the bad item when the position of the error is unknown (starting from 1)
+		Raw    string // raw content of the item (the CSV records are re-encoded)
+		Err    error  // the decoding error
+	}
+
+	// DecodeErrorPolicy defines what happens when an input can't decode an item
+	DecodeErrorPolicy struct {
+		Mode       DecodeErrorMode    // handling of the bad items
+		MaxBadRows int                // the input fails when it has more bad items in DecodeCollect mode (unlimited when 0)
+		OnError    func(*DecodeError) // receives the bad items in DecodeCollect mode
 	}
+
+	// DecodeErrorMode defines the handling of the items which can't be decoded by an input
+	DecodeErrorMode uint8
+
+	// StepPanicError holds a panic recovered from a step, and it matches [ErrStepPanicked]
+	StepPanicError struct {
+		Step     string // name of the step
+		Position int    // position of the step in the chain (starting from 1)
+		Value    any    // the recovered panic value
+		Stack    []byte // stack trace of the panic
+	}
+
+	// InputSource is a closable input (like a file or a database cursor) used by [TransformSource].
+	// Next returns io.EOF when the source is exhausted. The source could return an error for an item,
+	// and continue with the next item on the next call.
+	InputSource[T any] interface {
+		Open(ctx context.Context) error // prepares the source before reading the first item
+		Next() (T, error)               // returns the next item of the source
+		Close() error                   // releases the resources of the source
+		Meta() SourceMeta               // returns the metadata of the source
+	}
+
+	// SourceMeta holds the metadata of an input source
+	SourceMeta struct {
+		Name   string // name of the source (like the file path)
+		Offset int64  // byte offset of the consumed input
+	}
+
+	// JsonMode defines the format of a streaming JSON input
+	JsonMode uint8
+
+	// ErrorPolicy defines what happens when a step returns an error for an item
+	ErrorPolicy uint8
+
+	// FailedItem holds an item failed by a step, and it is passed to the dead-letter sink
+	FailedItem struct {
+		Error    error  // error returned by the step
+		Step     string // name of the failed step
+		Position int    // position of the failed step in the chain (starting from 1)
+		Args     Args   // input arguments of the failed step
+		ArgsLen  uint8  // length of the input arguments
+	}
+)
+
+const (
+	DefaultErrorPolicy ErrorPolicy = iota // steps are using the transformer error policy, and the transformer stops
+	Stop                                  // the error is passed to the error handler and the processing stops
+	Continue                              // the error is passed to the error handler and the failed item is dropped
+	DeadLetter                            // the failed item is passed to the dead-letter sink and the processing continues
+)
+
+const (
+	DefaultDecodeMode DecodeErrorMode = iota // the streaming inputs are skipping the bad items and pass them to the panic handler, other inputs fail
+	DecodeFail                               // the input fails on the first bad item
+	DecodeSkip                               // the bad items are skipped silently
+	DecodeCollect                            // the bad items are passed to OnError and skipped, and the input fails when there are more than MaxBadRows
+)
+
+const (
+	JsonLines        JsonMode = iota // every line holds a single item (the empty lines and the lines with a single bracket are skipped, the trailing commas are trimmed)
+	JsonArray                        // the items are the elements of a top-level JSON array, and they could be formatted arbitrarily
+	JsonConcatenated                 // the items are concatenated JSON values (like NDJSON) separated by any whitespace
+	JsonAuto                         // JsonArray when the input starts with a bracket, otherwise JsonConcatenated
 )
 
 var (
@@ -87,4 +199,14 @@ var (
 	ErrIncompatibleInArgType = errors.New("incompatible input argument type") // the outputs of the previous step don't match the inputs of the current step
 	ErrInvalidAggregator     = errors.New("invalid aggregator")               // aggregator has no reducer or name defined
 	ErrInvalidStep           = errors.New("invalid step")                     // step has no step or name defined
+	ErrInvalidStepConfig     = errors.New("invalid step configuration")       // step was created with invalid arguments (like a negative size)
+	ErrStepPanicked          = errors.New("step panicked")                    // step panicked while processing an item
+	ErrStepTimeout           = errors.New("step timed out")                   // step didn't finish processing an item within the timeout
+	ErrCircuitOpen           = errors.New("circuit open")                     // circuit breaker is open and it has no fallback step
+	ErrInvalidFallback       = errors.New("invalid fallback step")            // fallback step is not compatible with the wrapped step
+	ErrIncompatibleOutType   = errors.New("incompatible output type")         // the outputs of the transformer don't match the requested output type
+	ErrInputStopped          = errors.New("input stopped")                    // cause of the canceled input context when the transformer finished processing
+	ErrDecodeFailed          = errors.New("decode failed")                    // an input item can't be decoded
+	ErrTooManyBadRows        = errors.New("too many bad rows")                // the input has more bad items than allowed by the decode error policy
+	ErrInvalidJsonPath       = errors.New("invalid JSON path")                // the JSON path of the input can't be parsed
 )
@@ -1,65 +1,409 @@
 package steps
 
 import (
+	"context"
 	"fmt"
+	"iter"
+	"maps"
 	"reflect"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Map transforms a single input into a single output
 func Map[IN0, OUT0 any](fn func(in IN0) (OUT0, error)) StepWrapper {
-	return StepWrapper{
-		Name: "Map",
+	mapFn := MapCtx(func(_ context.Context, in IN0) (OUT0, error) {
+		return fn(in)
+	})
+	mapFn.Name = "Map"
+	return mapFn
+}
+
+// MapCtx is a variant of [Map] passing the context of the transformer to the function
+func MapCtx[IN0, OUT0 any](fn func(ctx context.Context, in IN0) (OUT0, error)) StepWrapper {
+	return withKernel(StepWrapper{
+		Name: "MapCtx",
 		StepFn: func(in StepInput) StepOutput {
-			out, err := fn(in.Args[0].(IN0))
+			out, err := fn(ctxOf(in), in.Args[0].(IN0))
 			return StepOutput{
 				Args:    Args{out},
 				ArgsLen: 1,
 				Error:   err,
 			}
 		},
-		Validate: func(prevStepOut ArgTypes) (ArgTypes, error) {
-			inArgTypes := ArgTypes{reflect.TypeFor[IN0]()}
-			for i := range maxArgs {
-				if i == 0 && prevStepOut[0] == reflect.TypeFor[SkipFirstArgValidation]() {
-					continue
-				}
-				if prevStepOut[i] != inArgTypes[i] {
-					return ArgTypes{}, fmt.Errorf("%w [%s!=%s:%d]", ErrIncompatibleInArgType, prevStepOut[i].String(), inArgTypes[i].String(), i+1)
-				}
-			}
-			return ArgTypes{reflect.TypeFor[OUT0]()}, nil
-		},
+		Validate: simpleMapValidation[IN0, OUT0],
+	}, newStepKernel(func(ctx context.Context, in IN0) (OUT0, bool, error) {
+		out, err := fn(ctx, in)
+		return out, true, err
+	}))
+}
+
+// ctxOf returns the context of the transformer passed to the step
+func ctxOf(in StepInput) context.Context {
+	if in.TransformerOptions.Ctx == nil {
+		return context.Background()
 	}
+	return in.TransformerOptions.Ctx
 }
 
-func simpleFilterValidation[IN0 any](prevStepOut ArgTypes) (ArgTypes, error) {
-	inArgTypes := ArgTypes{reflect.TypeFor[IN0]()}
+func simpleMapValidation[IN0, OUT0 any](prevStepOut ArgTypes) (ArgTypes, error) {
+	if err := argTypesValidation(prevStepOut, ArgTypes{reflect.TypeFor[IN0]()}); err != nil {
+		return ArgTypes{}, err
+	}
+	return ArgTypes{reflect.TypeFor[OUT0]()}, nil
+}
+
+// argTypesValidation checks all the argument positions of the previous step outputs against the step inputs
+func argTypesValidation(prevStepOut, inArgTypes ArgTypes) error {
 	for i := range maxArgs {
 		if i == 0 && prevStepOut[0] == reflect.TypeFor[SkipFirstArgValidation]() {
 			continue
 		}
 		if prevStepOut[i] != inArgTypes[i] {
-			return ArgTypes{}, fmt.Errorf("%w [%s!=%s:%d]", ErrIncompatibleInArgType, prevStepOut[i].String(), inArgTypes[i].String(), i+1)
+			return fmt.Errorf("%w [%s!=%s:%d]", ErrIncompatibleInArgType, argTypeName(prevStepOut[i]), argTypeName(inArgTypes[i]), i+1)
 		}
 	}
+	return nil
+}
+
+func argTypeName(argType reflect.Type) string {
+	if argType == nil {
+		return "<nil>"
+	}
+	return argType.String()
+}
+
+// ParallelMap transforms a single input into a single output using a pool of workers.
+// Up to the given number of inputs are transformed concurrently, but the outputs are passed to the next step in the input order.
+// The outputs still in progress are emitted when the input is exhausted, and a channel input also emits the completed outputs
+// while it's waiting for the next item (see [StepWrapper.Ready]).
+// The failed inputs are handled by the error policy in the input order, and the later inputs are still processed.
+func ParallelMap[IN0, OUT0 any](workers int, fn func(in IN0) (OUT0, error)) StepWrapper {
+	parallelMapFn := ParallelMapCtx(workers, func(_ context.Context, in IN0) (OUT0, error) {
+		return fn(in)
+	})
+	parallelMapFn.Name = "ParallelMap"
+	return parallelMapFn
+}
+
+// ParallelMapCtx is a variant of [ParallelMap] passing the context of the transformer to the function
+func ParallelMapCtx[IN0, OUT0 any](workers int, fn func(ctx context.Context, in IN0) (OUT0, error)) StepWrapper {
+	workers = max(workers, 1)
+	type task struct {
+		in   Args
+		res  StepOutput
+		done chan struct{} // closed when res is set
+	}
+	var pending []*task
+	// awaitFirst waits for the oldest pending input, and adds it's output (or the failed input with it's error) to the outputs
+	awaitFirst := func(ctx context.Context, out *StepOutput) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-pending[0].done:
+			res := pending[0].res
+			if res.Error != nil {
+				res.Args = pending[0].in
+			}
+			pending = pending[1:]
+			out.MultiArgs = append(out.MultiArgs, res.Args)
+			out.MultiErrors = append(out.MultiErrors, res.Error)
+			return nil
+		}
+	}
+	mapFn := func(in StepInput) StepOutput {
+		res, err := fn(ctxOf(in), in.Args[0].(IN0))
+		return StepOutput{
+			Args:    Args{res},
+			ArgsLen: 1,
+			Error:   err,
+		}
+	}
+
+	return StepWrapper{
+		Name: "ParallelMapCtx",
+		StepFn: func(in StepInput) StepOutput {
+			out := StepOutput{ArgsLen: 1}
+			if len(pending) == workers {
+				if err := awaitFirst(in.TransformerOptions.Ctx, &out); err != nil {
+					return StepOutput{Error: err}
+				}
+			}
+
+			// the input is always dispatched, so it's not lost when the awaited input failed
+			dispatched := &task{in: in.Args, done: make(chan struct{})}
+			go func() {
+				dispatched.res = recoverStep(mapFn, in)
+				close(dispatched.done)
+			}()
+			pending = append(pending, dispatched)
+
+			if out.MultiArgs == nil {
+				out.Skip = true
+			}
+			return out
+		},
+		Flush: func(opts TransformerOptions) StepOutput {
+			flushed := StepOutput{
+				MultiArgs: make([]Args, 0, len(pending)),
+				ArgsLen:   1,
+			}
+			for len(pending) > 0 {
+				if err := awaitFirst(opts.Ctx, &flushed); err != nil {
+					return StepOutput{Error: err}
+				}
+			}
+			return flushed
+		},
+		Ready: func() <-chan struct{} {
+			if len(pending) == 0 {
+				return nil
+			}
+			return pending[0].done
+		},
+		FlushReady: func(opts TransformerOptions) StepOutput {
+			ready := StepOutput{MultiArgs: []Args{}, ArgsLen: 1}
+			for len(pending) > 0 && isClosed(pending[0].done) {
+				if err := awaitFirst(opts.Ctx, &ready); err != nil {
+					return StepOutput{Error: err}
+				}
+			}
+			return ready
+		},
+		Validate: simpleMapValidation[IN0, OUT0],
+		Reset: func() {
+			pending = nil
+		},
+	}
+}
+
+// Map1To2 transforms a single input into two outputs (like a key and a value)
+func Map1To2[IN0, OUT0, OUT1 any](fn func(in IN0) (OUT0, OUT1, error)) StepWrapper {
+	map1To2Fn := Map1To2Ctx(func(_ context.Context, in IN0) (OUT0, OUT1, error) {
+		return fn(in)
+	})
+	map1To2Fn.Name = "Map1To2"
+	return map1To2Fn
+}
+
+// Map1To2Ctx is a variant of [Map1To2] passing the context of the transformer to the function
+func Map1To2Ctx[IN0, OUT0, OUT1 any](fn func(ctx context.Context, in IN0) (OUT0, OUT1, error)) StepWrapper {
+	return StepWrapper{
+		Name: "Map1To2Ctx",
+		StepFn: func(in StepInput) StepOutput {
+			out0, out1, err := fn(ctxOf(in), in.Args[0].(IN0))
+			return StepOutput{
+				Args:    Args{out0, out1},
+				ArgsLen: 2,
+				Error:   err,
+			}
+		},
+		Validate: func(prevStepOut ArgTypes) (ArgTypes, error) {
+			if err := argTypesValidation(prevStepOut, ArgTypes{reflect.TypeFor[IN0]()}); err != nil {
+				return ArgTypes{}, err
+			}
+			return ArgTypes{reflect.TypeFor[OUT0](), reflect.TypeFor[OUT1]()}, nil
+		},
+	}
+}
+
+// Map2To1 transforms two inputs into a single output
+func Map2To1[IN0, IN1, OUT0 any](fn func(in0 IN0, in1 IN1) (OUT0, error)) StepWrapper {
+	map2To1Fn := Map2To1Ctx(func(_ context.Context, in0 IN0, in1 IN1) (OUT0, error) {
+		return fn(in0, in1)
+	})
+	map2To1Fn.Name = "Map2To1"
+	return map2To1Fn
+}
+
+// Map2To1Ctx is a variant of [Map2To1] passing the context of the transformer to the function
+func Map2To1Ctx[IN0, IN1, OUT0 any](fn func(ctx context.Context, in0 IN0, in1 IN1) (OUT0, error)) StepWrapper {
+	return StepWrapper{
+		Name: "Map2To1Ctx",
+		StepFn: func(in StepInput) StepOutput {
+			out, err := fn(ctxOf(in), in.Args[0].(IN0), in.Args[1].(IN1))
+			return StepOutput{
+				Args:    Args{out},
+				ArgsLen: 1,
+				Error:   err,
+			}
+		},
+		Validate: func(prevStepOut ArgTypes) (ArgTypes, error) {
+			if err := argTypesValidation(prevStepOut, ArgTypes{reflect.TypeFor[IN0](), reflect.TypeFor[IN1]()}); err != nil {
+				return ArgTypes{}, err
+			}
+			return ArgTypes{reflect.TypeFor[OUT0]()}, nil
+		},
+	}
+}
+
+// Map2To2 transforms two inputs into two outputs
+func Map2To2[IN0, IN1, OUT0, OUT1 any](fn func(in0 IN0, in1 IN1) (OUT0, OUT1, error)) StepWrapper {
+	map2To2Fn := Map2To2Ctx(func(_ context.Context, in0 IN0, in1 IN1) (OUT0, OUT1, error) {
+		return fn(in0, in1)
+	})
+	map2To2Fn.Name = "Map2To2"
+	return map2To2Fn
+}
+
+// Map2To2Ctx is a variant of [Map2To2] passing the context of the transformer to the function
+func Map2To2Ctx[IN0, IN1, OUT0, OUT1 any](fn func(ctx context.Context, in0 IN0, in1 IN1) (OUT0, OUT1, error)) StepWrapper {
+	return StepWrapper{
+		Name: "Map2To2Ctx",
+		StepFn: func(in StepInput) StepOutput {
+			out0, out1, err := fn(ctxOf(in), in.Args[0].(IN0), in.Args[1].(IN1))
+			return StepOutput{
+				Args:    Args{out0, out1},
+				ArgsLen: 2,
+				Error:   err,
+			}
+		},
+		Validate: func(prevStepOut ArgTypes) (ArgTypes, error) {
+			if err := argTypesValidation(prevStepOut, ArgTypes{reflect.TypeFor[IN0](), reflect.TypeFor[IN1]()}); err != nil {
+				return ArgTypes{}, err
+			}
+			return ArgTypes{reflect.TypeFor[OUT0](), reflect.TypeFor[OUT1]()}, nil
+		},
+	}
+}
+
+// FlatMap transforms a single input into zero or more outputs.
+// The outputs are passed one by one to the next steps, and they are discarded when the function returns an error.
+func FlatMap[IN0, OUT0 any](fn func(in IN0) ([]OUT0, error)) StepWrapper {
+	flatMapFn := FlatMapCtx(func(_ context.Context, in IN0) ([]OUT0, error) {
+		return fn(in)
+	})
+	flatMapFn.Name = "FlatMap"
+	return flatMapFn
+}
+
+// FlatMapCtx is a variant of [FlatMap] passing the context of the transformer to the function
+func FlatMapCtx[IN0, OUT0 any](fn func(ctx context.Context, in IN0) ([]OUT0, error)) StepWrapper {
+	return StepWrapper{
+		Name: "FlatMapCtx",
+		StepFn: func(in StepInput) StepOutput {
+			outs, err := fn(ctxOf(in), in.Args[0].(IN0))
+			multiArgs := make([]Args, len(outs))
+			for i, out := range outs {
+				multiArgs[i] = Args{out}
+			}
+			return StepOutput{
+				MultiArgs: multiArgs,
+				ArgsLen:   1,
+				Error:     err,
+			}
+		},
+		Validate: simpleMapValidation[IN0, OUT0],
+	}
+}
+
+// FlatMapSeq transforms a single input into a sequence of outputs.
+// The outputs are passed one by one to the next steps, and they are discarded when the function returns an error.
+// The sequence is pulled lazily, so it could be infinite when the processing is stopped by a next step (like [Take])
+// or by the consumer. Inside the branches the sequence is collected, because the outputs of a branch are emitted together.
+func FlatMapSeq[IN0, OUT0 any](fn func(in IN0) (iter.Seq[OUT0], error)) StepWrapper {
+	flatMapSeqFn := FlatMapSeqCtx(func(_ context.Context, in IN0) (iter.Seq[OUT0], error) {
+		return fn(in)
+	})
+	flatMapSeqFn.Name = "FlatMapSeq"
+	return flatMapSeqFn
+}
+
+// FlatMapSeqCtx is a variant of [FlatMapSeq] passing the context of the transformer to the function
+func FlatMapSeqCtx[IN0, OUT0 any](fn func(ctx context.Context, in IN0) (iter.Seq[OUT0], error)) StepWrapper {
+	return StepWrapper{
+		Name: "FlatMapSeqCtx",
+		StepFn: func(in StepInput) StepOutput {
+			outs, err := fn(ctxOf(in), in.Args[0].(IN0))
+			if err != nil || outs == nil {
+				return StepOutput{
+					MultiArgs: []Args{},
+					ArgsLen:   1,
+					Error:     err,
+				}
+			}
+			return StepOutput{
+				MultiSeq: func(yield func(Args) bool) {
+					for out := range outs {
+						if !yield(Args{out}) {
+							return
+						}
+					}
+				},
+				ArgsLen: 1,
+			}
+		},
+		Validate: simpleMapValidation[IN0, OUT0],
+	}
+}
+
+func simpleFilterValidation[IN0 any](prevStepOut ArgTypes) (ArgTypes, error) {
+	if err := argTypesValidation(prevStepOut, ArgTypes{reflect.TypeFor[IN0]()}); err != nil {
+		return ArgTypes{}, err
+	}
 	return ArgTypes{reflect.TypeFor[IN0]()}, nil
 }
 
 // Filter skips inputs that do not pass the filter
 func Filter[IN0 any](fn func(in IN0) (bool, error)) StepWrapper {
-	return StepWrapper{
-		Name: "Filter",
+	filterFn := FilterCtx(func(_ context.Context, in IN0) (bool, error) {
+		return fn(in)
+	})
+	filterFn.Name = "Filter"
+	return filterFn
+}
+
+// FilterCtx is a variant of [Filter] passing the context of the transformer to the function
+func FilterCtx[IN0 any](fn func(ctx context.Context, in IN0) (bool, error)) StepWrapper {
+	return withKernel(StepWrapper{
+		Name: "FilterCtx",
 		StepFn: func(in StepInput) StepOutput {
-			ok, err := fn(in.Args[0].(IN0))
+			ok, err := fn(ctxOf(in), in.Args[0].(IN0))
 			return StepOutput{
-				Args:    Args{in.Args[0].(IN0)},
+				Args:    Args{in.Args[0]},
 				ArgsLen: 1,
 				Error:   err,
 				Skip:    !ok,
 			}
 		},
 		Validate: simpleFilterValidation[IN0],
+	}, newStepKernel(func(ctx context.Context, in IN0) (IN0, bool, error) {
+		ok, err := fn(ctx, in)
+		return in, ok, err
+	}))
+}
+
+// Filter2 skips the pair of inputs that do not pass the filter
+func Filter2[IN0, IN1 any](fn func(in0 IN0, in1 IN1) (bool, error)) StepWrapper {
+	filter2Fn := Filter2Ctx(func(_ context.Context, in0 IN0, in1 IN1) (bool, error) {
+		return fn(in0, in1)
+	})
+	filter2Fn.Name = "Filter2"
+	return filter2Fn
+}
+
+// Filter2Ctx is a variant of [Filter2] passing the context of the transformer to the function
+func Filter2Ctx[IN0, IN1 any](fn func(ctx context.Context, in0 IN0, in1 IN1) (bool, error)) StepWrapper {
+	return StepWrapper{
+		Name: "Filter2Ctx",
+		StepFn: func(in StepInput) StepOutput {
+			ok, err := fn(ctxOf(in), in.Args[0].(IN0), in.Args[1].(IN1))
+			return StepOutput{
+				Args:    Args{in.Args[0], in.Args[1]},
+				ArgsLen: 2,
+				Error:   err,
+				Skip:    !ok,
+			}
+		},
+		Validate: func(prevStepOut ArgTypes) (ArgTypes, error) {
+			inArgTypes := ArgTypes{reflect.TypeFor[IN0](), reflect.TypeFor[IN1]()}
+			if err := argTypesValidation(prevStepOut, inArgTypes); err != nil {
+				return ArgTypes{}, err
+			}
+			return inArgTypes, nil
+		},
 	}
 }
 
@@ -72,9 +416,10 @@ func Take[IN0 any](count uint64) StepWrapper {
 			skip := counter >= count
 			counter++
 			return StepOutput{
-				Args:    Args{in.Args[0].(IN0)},
+				Args:    Args{in.Args[0]},
 				ArgsLen: 1,
 				Skip:    skip,
+				Done:    counter >= count,
 			}
 		},
 		Validate: simpleFilterValidation[IN0],
@@ -86,19 +431,29 @@ func Take[IN0 any](count uint64) StepWrapper {
 
 // TakeWhile processes inputs while the filter returns true
 func TakeWhile[IN0 any](fn func(in IN0) (bool, error)) StepWrapper {
+	takeWhileFn := TakeWhileCtx(func(_ context.Context, in IN0) (bool, error) {
+		return fn(in)
+	})
+	takeWhileFn.Name = "TakeWhile"
+	return takeWhileFn
+}
+
+// TakeWhileCtx is a variant of [TakeWhile] passing the context of the transformer to the function
+func TakeWhileCtx[IN0 any](fn func(ctx context.Context, in IN0) (bool, error)) StepWrapper {
 	var skip bool
 	return StepWrapper{
-		Name: "TakeWhile",
+		Name: "TakeWhileCtx",
 		StepFn: func(in StepInput) StepOutput {
-			ok, err := fn(in.Args[0].(IN0))
+			ok, err := fn(ctxOf(in), in.Args[0].(IN0))
 			if !skip && !ok {
 				skip = true
 			}
 			return StepOutput{
-				Args:    Args{in.Args[0].(IN0)},
+				Args:    Args{in.Args[0]},
 				ArgsLen: 1,
 				Error:   err,
 				Skip:    skip,
+				Done:    skip,
 			}
 		},
 		Validate: simpleFilterValidation[IN0],
@@ -117,7 +472,7 @@ func Skip[IN0 any](count uint64) StepWrapper {
 			skip := counter < count
 			counter++
 			return StepOutput{
-				Args:    Args{in.Args[0].(IN0)},
+				Args:    Args{in.Args[0]},
 				ArgsLen: 1,
 				Skip:    skip,
 			}
@@ -131,16 +486,25 @@ func Skip[IN0 any](count uint64) StepWrapper {
 
 // SkipWhile skips processing inputs while the filter returns true
 func SkipWhile[IN0 any](fn func(in IN0) (bool, error)) StepWrapper {
+	skipWhileFn := SkipWhileCtx(func(_ context.Context, in IN0) (bool, error) {
+		return fn(in)
+	})
+	skipWhileFn.Name = "SkipWhile"
+	return skipWhileFn
+}
+
+// SkipWhileCtx is a variant of [SkipWhile] passing the context of the transformer to the function
+func SkipWhileCtx[IN0 any](fn func(ctx context.Context, in IN0) (bool, error)) StepWrapper {
 	skip := true
 	return StepWrapper{
-		Name: "SkipWhile",
+		Name: "SkipWhileCtx",
 		StepFn: func(in StepInput) StepOutput {
-			ok, err := fn(in.Args[0].(IN0))
+			ok, err := fn(ctxOf(in), in.Args[0].(IN0))
 			if skip && !ok {
 				skip = false
 			}
 			return StepOutput{
-				Args:    Args{in.Args[0].(IN0)},
+				Args:    Args{in.Args[0]},
 				ArgsLen: 1,
 				Error:   err,
 				Skip:    skip,
@@ -153,12 +517,85 @@ func SkipWhile[IN0 any](fn func(in IN0) (bool, error)) StepWrapper {
 	}
 }
 
+// Batch groups the inputs into slices of the given size.
+// A batch is also emitted when it's older than maxWait or when the input is exhausted.
+// A channel input emits the batch on time while it's waiting for the next input, other inputs (like iter.Seq)
+// are blocking the transformer while they are waiting, so their batch is emitted when the next input arrives.
+// A zero size or maxWait disables the given limit, and the validation fails with [ErrInvalidStepConfig] for a negative size.
+func Batch[IN0 any](size int, maxWait time.Duration) StepWrapper {
+	var batch []IN0
+	var started time.Time
+	return StepWrapper{
+		Name: "Batch",
+		StepFn: func(in StepInput) StepOutput {
+			var batches []Args
+			if len(batch) > 0 && maxWait > 0 && time.Since(started) >= maxWait {
+				batches = append(batches, Args{batch})
+				batch = nil
+			}
+			if batch == nil {
+				batch = make([]IN0, 0, size)
+				started = time.Now()
+			}
+
+			batch = append(batch, in.Args[0].(IN0))
+			if len(batch) == size {
+				batches = append(batches, Args{batch})
+				batch = nil
+			}
+
+			if batches == nil {
+				return StepOutput{Skip: true}
+			}
+			return StepOutput{
+				MultiArgs: batches,
+				ArgsLen:   1,
+			}
+		},
+		Flush: func(TransformerOptions) StepOutput {
+			if len(batch) == 0 {
+				return StepOutput{Skip: true}
+			}
+			out := StepOutput{
+				Args:    Args{batch},
+				ArgsLen: 1,
+			}
+			batch = nil
+			return out
+		},
+		FlushAt: func() time.Time {
+			if len(batch) == 0 || maxWait <= 0 {
+				return time.Time{}
+			}
+			return started.Add(maxWait)
+		},
+		Validate: func(prevStepOut ArgTypes) (ArgTypes, error) {
+			if size < 0 {
+				return ArgTypes{}, fmt.Errorf("%w [size:%d]", ErrInvalidStepConfig, size)
+			}
+			return simpleMapValidation[IN0, []IN0](prevStepOut)
+		},
+		Reset: func() {
+			batch = nil
+		},
+	}
+}
+
 // Do runs a function on each input item
 func Do[IN0 any](fn func(in IN0) error) StepWrapper {
-	return StepWrapper{
-		Name: "Do",
+	doFn := DoCtx(func(_ context.Context, in IN0) error {
+		return fn(in)
+	})
+	doFn.Name = "Do"
+	return doFn
+}
+
+// DoCtx is a variant of [Do] passing the context of the transformer to the function
+func DoCtx[IN0 any](fn func(ctx context.Context, in IN0) error) StepWrapper {
+	return withKernel(StepWrapper{
+		Name: "DoCtx",
 		StepFn: func(in StepInput) StepOutput {
-			err := fn(in.Args[0].(IN0))
+			err := fn(ctxOf(in), in.Args[0].(IN0))
 			return StepOutput{
 				Args:    in.Args,
 				ArgsLen: in.ArgsLen,
@@ -166,7 +603,9 @@ func Do[IN0 any](fn func(in IN0) error) StepWrapper {
 			}
 		},
 		Validate: simpleFilterValidation[IN0],
-	}
+	}, newStepKernel(func(ctx context.Context, in IN0) (IN0, bool, error) {
+		return in, true, fn(ctx, in)
+	}))
 }
 
 // Log logs debug informations between steps
@@ -210,21 +649,58 @@ func Log(prefix ...string) StepWrapper {
 	}
 }
 
-type branch struct {
-	value any
-	T     reflect.Value
-	key   uint8
-}
+type (
+	branch struct {
+		value   any
+		values  []any // the outputs of a branch with multiple outputs (like FlatMap), the value is not used when it's not nil
+		T       reflect.Value
+		key     uint8
+		seq     uint64 // position of the input when processed by parallel branches (starting from 1)
+		skipped bool   // the input was skipped by a parallel branch (used to keep the order when merging)
+	}
+
+	branchResult struct {
+		in     branch // the input of the branch
+		value  branch
+		failed StepOutput // the failed values of the branch with their errors (as multiple outputs)
+		err    error
+	}
+
+	// MergeOrder defines the order of the outputs merged back from parallel branches
+	MergeOrder uint8
+)
+
+const (
+	InputOrder      MergeOrder = iota // the outputs are merged in the order of the inputs
+	CompletionOrder                   // the outputs are merged in the order the branches completed them
+)
 
 var branchType = reflect.TypeFor[branch]()
 
+// unwrapBranch returns the arguments with the branch input replaced by it's original argument
+func unwrapBranch(args Args) Args {
+	if b, ok := args[0].(branch); ok {
+		args[0] = b.value
+	}
+	return args
+}
+
 // Split defines how to split inputs into transformation branches
 // The function returns the number of the branch where the input will be sent
 func Split[IN0 any, OUT0 ~uint8](fn func(in IN0) (OUT0, error)) StepWrapper {
+	splitFn := SplitCtx(func(_ context.Context, in IN0) (OUT0, error) {
+		return fn(in)
+	})
+	splitFn.Name = "Split"
+	return splitFn
+}
+
+// SplitCtx is a variant of [Split] passing the context of the transformer to the function
+func SplitCtx[IN0 any, OUT0 ~uint8](fn func(ctx context.Context, in IN0) (OUT0, error)) StepWrapper {
 	return StepWrapper{
-		Name: "Split",
+		Name: "SplitCtx",
 		StepFn: func(in StepInput) StepOutput {
-			idx, err := fn(in.Args[0].(IN0))
+			idx, err := fn(ctxOf(in), in.Args[0].(IN0))
 			out := branch{key: uint8(idx), value: in.Args[0].(IN0), T: reflect.ValueOf(in.Args[0])}
 			return StepOutput{
 				Args:    Args{out},
@@ -233,93 +709,533 @@ func Split[IN0 any, OUT0 ~uint8](fn func(in IN0) (OUT0, error)) StepWrapper {
 			}
 		},
 		Validate: func(prevStepOut ArgTypes) (ArgTypes, error) {
-			inArgTypes := ArgTypes{reflect.TypeFor[IN0]()}
-			for i := range maxArgs {
-				if i == 0 && prevStepOut[0] == reflect.TypeFor[SkipFirstArgValidation]() {
-					continue
-				}
-				if prevStepOut[i] != inArgTypes[i] {
-					return ArgTypes{}, fmt.Errorf("%w [%s!=%s:%d]", ErrIncompatibleInArgType, prevStepOut[i].String(), inArgTypes[i].String(), i+1)
-				}
+			if err := argTypesValidation(prevStepOut, ArgTypes{reflect.TypeFor[IN0]()}); err != nil {
+				return ArgTypes{}, err
 			}
 			return ArgTypes{branchType}, nil
 		},
 	}
 }
 
+// processBranch runs the steps of a branch on the input.
+// The multiple outputs of the steps (like FlatMap) are passed one by one to the next steps of the branch,
+// so the output is holding multiple outputs when any of the steps had multiple outputs.
+func processBranch(stepWrappers []StepWrapper, in StepInput) StepOutput {
+	for pos, stepWrapper := range stepWrappers {
+		out := stepWrapper.StepFn(in)
+		switch {
+		case out.Error != nil:
+			return out
+		case out.MultiSeq != nil || out.MultiArgs != nil:
+			return processBranchOutputs(stepWrappers[pos+1:], in.TransformerOptions, out)
+		case out.Skip:
+			return out
+		}
+		in = StepInput{
+			Args:               out.Args,
+			ArgsLen:            out.ArgsLen,
+			TransformerOptions: in.TransformerOptions,
+		}
+	}
+	return StepOutput{
+		Args:    in.Args,
+		ArgsLen: in.ArgsLen,
+	}
+}
+
+// processBranchOutputs passes the multiple outputs of a branch step to the next steps of the branch one by one.
+// The failed outputs are holding the input arguments of the failed step.
+func processBranchOutputs(stepWrappers []StepWrapper, opts TransformerOptions, out StepOutput) StepOutput {
+	multiArgs := out.MultiArgs
+	if out.MultiSeq != nil {
+		multiArgs = slices.Collect(out.MultiSeq)
+	}
+
+	res := StepOutput{
+		MultiArgs: []Args{},
+		ArgsLen:   out.ArgsLen,
+	}
+	for i, args := range multiArgs {
+		err := multiError(out, i)
+		if err == nil {
+			next := processBranch(stepWrappers, StepInput{
+				Args:               args,
+				ArgsLen:            out.ArgsLen,
+				TransformerOptions: opts,
+			})
+			switch {
+			case next.Error != nil:
+				err = next.Error
+			case next.MultiArgs != nil:
+				for j, nextArgs := range next.MultiArgs {
+					res.MultiArgs = append(res.MultiArgs, nextArgs)
+					res.MultiErrors = append(res.MultiErrors, multiError(next, j))
+				}
+				continue
+			case next.Skip:
+				continue
+			default:
+				args = next.Args
+			}
+		}
+		res.MultiArgs = append(res.MultiArgs, args)
+		res.MultiErrors = append(res.MultiErrors, err)
+	}
+	return res
+}
+
+// multiError returns the error of the multiple output at idx (nil for the succeeded outputs)
+func multiError(out StepOutput, idx int) error {
+	if idx < len(out.MultiErrors) {
+		return out.MultiErrors[idx]
+	}
+	return nil
+}
+
+// runBranch runs the steps of a branch on the value of the branch item (or on each of it's values).
+// It returns the branch item holding the outputs (skipped when there is none),
+// and the failed values with their errors as multiple outputs (the failed values are the inputs of the branch).
+func runBranch(stepWrappers []StepWrapper, item branch, opts TransformerOptions) (branch, StepOutput) {
+	failed := StepOutput{ArgsLen: 1}
+	values, isMulti := item.values, item.values != nil
+	if !isMulti {
+		values = []any{item.value}
+	}
+
+	var outValues []any
+	for _, value := range values {
+		out := processBranch(stepWrappers, StepInput{
+			Args:               Args{value},
+			ArgsLen:            1,
+			TransformerOptions: opts,
+		})
+		switch {
+		case out.Error != nil:
+			failed.MultiArgs = append(failed.MultiArgs, Args{value})
+			failed.MultiErrors = append(failed.MultiErrors, out.Error)
+		case out.MultiArgs != nil:
+			isMulti = true
+			for i, args := range out.MultiArgs {
+				if err := multiError(out, i); err != nil {
+					failed.MultiArgs = append(failed.MultiArgs, Args{value})
+					failed.MultiErrors = append(failed.MultiErrors, err)
+					continue
+				}
+				outValues = append(outValues, args[0])
+			}
+		case !out.Skip:
+			outValues = append(outValues, out.Args[0])
+		}
+	}
+
+	item.values, item.value, item.T = nil, nil, reflect.Value{}
+	switch {
+	case isMulti:
+		item.values = append([]any{}, outValues...)
+	case len(outValues) == 1:
+		item.value, item.T = outValues[0], reflect.ValueOf(outValues[0])
+	}
+	item.skipped = len(outValues) == 0
+	return item, failed
+}
+
+// flushBranch flushes the steps of a branch in order, and passes the flushed outputs to the next steps of the branch
+func flushBranch(stepWrappers []StepWrapper, opts TransformerOptions) StepOutput {
+	res := StepOutput{
+		MultiArgs: []Args{},
+		ArgsLen:   1,
+	}
+	for pos, stepWrapper := range stepWrappers {
+		if stepWrapper.Flush == nil {
+			continue
+		}
+		out := stepWrapper.Flush(opts)
+		switch {
+		case out.Error != nil:
+			return out
+		case out.MultiSeq == nil && out.MultiArgs == nil && out.Skip:
+			continue
+		case out.MultiSeq == nil && out.MultiArgs == nil:
+			out.MultiArgs = []Args{out.Args}
+		}
+		next := processBranchOutputs(stepWrappers[pos+1:], opts, out)
+		res.MultiArgs = append(res.MultiArgs, next.MultiArgs...)
+		for i := range next.MultiArgs {
+			res.MultiErrors = append(res.MultiErrors, multiError(next, i))
+		}
+	}
+	return res
+}
+
+// flushBranches flushes the steps of every branch, and returns the flushed outputs as branch items
+func flushBranches(stepsBranches []StepsBranch, opts TransformerOptions) StepOutput {
+	res := StepOutput{
+		MultiArgs: []Args{},
+		ArgsLen:   1,
+	}
+	for key, stepsBranch := range stepsBranches {
+		out := flushBranch(stepsBranch.StepWrappers, opts)
+		if out.Error != nil {
+			return out
+		}
+		for i, args := range out.MultiArgs {
+			if err := multiError(out, i); err != nil {
+				res.MultiArgs = append(res.MultiArgs, args)
+				res.MultiErrors = append(res.MultiErrors, err)
+				continue
+			}
+			res.MultiArgs = append(res.MultiArgs, Args{branch{key: uint8(key), value: args[0], T: reflect.ValueOf(args[0])}})
+			res.MultiErrors = append(res.MultiErrors, nil)
+		}
+	}
+	return res
+}
+
+// branchesState returns a function resetting the states of the branch steps and whether any of them has to be flushed
+func branchesState(stepsBranches []StepsBranch) (func(), bool) {
+	var (
+		resets   []func()
+		hasFlush bool
+	)
+	for _, stepsBranch := range stepsBranches {
+		for _, stepWrapper := range stepsBranch.StepWrappers {
+			if stepWrapper.Reset != nil {
+				resets = append(resets, stepWrapper.Reset)
+			}
+			hasFlush = hasFlush || stepWrapper.Flush != nil
+		}
+	}
+	if resets == nil {
+		return nil, hasFlush
+	}
+	return func() {
+		for _, reset := range resets {
+			reset()
+		}
+	}, hasFlush
+}
+
+func branchesValidation[IN0 any](stepsBranches []StepsBranch) func(prevStepOut ArgTypes) (ArgTypes, error) {
+	return func(prevStepOut ArgTypes) (ArgTypes, error) {
+		if err := argTypesValidation(prevStepOut, ArgTypes{branchType}); err != nil {
+			return ArgTypes{}, err
+		}
+		for _, container := range stepsBranches {
+			if _, _, err := getValidatedSteps[IN0](container.StepWrappers); err != nil {
+				return ArgTypes{}, err
+			}
+		}
+		return ArgTypes{branchType}, nil
+	}
+}
+
 // WithBranches applies a set of steps to each branch.
 // This is not parallel processing. The items keeps the order even if one branch could possibly process faster.
+// The steps of the branches could have multiple outputs (like FlatMap), and the buffering steps (like Batch) are flushed
+// when the input is exhausted.
+// See [WithParallelBranches] for processing the branches concurrently.
 func WithBranches[IN0 any](stepsBranches ...StepsBranch) StepWrapper {
-	return StepWrapper{
+	reset, hasFlush := branchesState(stepsBranches)
+	wrapper := StepWrapper{
 		Name: "WithBranches",
 		StepFn: func(in StepInput) StepOutput {
 			keyVal := in.Args[0].(branch)
-			val := StepInput{
-				Args:               Args{keyVal.value},
-				ArgsLen:            1,
-				TransformerOptions: in.TransformerOptions,
-			}
-			var out StepOutput
-			for _, stepWrapper := range stepsBranches[int(keyVal.key)].StepWrappers {
-				out = stepWrapper.StepFn(val)
-				val = StepInput{
-					Args:               out.Args,
-					ArgsLen:            out.ArgsLen,
-					TransformerOptions: in.TransformerOptions,
+			if keyVal.skipped {
+				return StepOutput{
+					Args:    in.Args,
+					ArgsLen: 1,
 				}
-				if out.Skip || out.Error != nil {
-					break
+			}
+
+			item, failed := runBranch(stepsBranches[int(keyVal.key)].StepWrappers, keyVal, in.TransformerOptions)
+			// the skipped outputs of parallel branches are kept, so Merge still receives every position of the input order
+			emitItem := !item.skipped || keyVal.seq != 0
+			if failed.MultiArgs == nil {
+				return StepOutput{
+					Args:    Args{item},
+					ArgsLen: 1,
+					Skip:    !emitItem,
 				}
 			}
-			return StepOutput{
-				Args:    Args{branch{key: keyVal.key, value: out.Args[0], T: reflect.ValueOf(out.Args[0])}},
-				ArgsLen: 1,
-				Error:   out.Error,
-				Skip:    out.Skip,
+			if len(failed.MultiArgs) == 1 && !emitItem {
+				return StepOutput{
+					Error: failed.MultiErrors[0],
+				}
+			}
+			if emitItem {
+				failed.MultiArgs = append(failed.MultiArgs, Args{item})
+				failed.MultiErrors = append(failed.MultiErrors, nil)
 			}
+			return failed
 		},
-		Validate: func(prevStepOut ArgTypes) (ArgTypes, error) {
-			inArgTypes := ArgTypes{branchType}
-			for i := range maxArgs {
-				if i == 0 && prevStepOut[0] == reflect.TypeFor[SkipFirstArgValidation]() {
-					continue
+		Validate: branchesValidation[IN0](stepsBranches),
+		Reset:    reset,
+	}
+	if hasFlush {
+		wrapper.Flush = func(opts TransformerOptions) StepOutput {
+			return flushBranches(stepsBranches, opts)
+		}
+	}
+	return wrapper
+}
+
+// WithParallelBranches applies a set of steps to each branch, where each branch is processed on it's own goroutine.
+// The branch outputs are passed to the next step in completion order, so [Merge] is used to restore the input order if needed.
+// The number of inputs processed at once is limited by the channel size of the transformer options.
+// The failed inputs are handled by the error policy, and they are passed to [Merge] as skipped outputs to keep the input order.
+// The multiple outputs of a branch (like the outputs of FlatMap) are merged together at the position of their input,
+// and the buffering steps of the branches (like Batch) are flushed when the input is exhausted.
+// A channel input also passes the completed branch outputs while it's waiting for the next item (see [StepWrapper.Ready]).
+func WithParallelBranches[IN0 any](stepsBranches ...StepsBranch) StepWrapper {
+	reset, hasFlush := branchesState(stepsBranches)
+	var (
+		queues   []chan branch
+		results  chan branchResult
+		limit    int
+		inFlight int
+		seq      uint64
+		readyMu  sync.Mutex
+		ready    chan struct{} // closed when a result is sent after it was created by Ready
+	)
+	notifyReady := func() {
+		readyMu.Lock()
+		defer readyMu.Unlock()
+		if ready != nil && !isClosed(ready) {
+			close(ready)
+		}
+	}
+
+	start := func(opts TransformerOptions) {
+		limit = max(int(opts.ChanSize), 1)
+		results = make(chan branchResult, limit)
+		queues = make([]chan branch, len(stepsBranches))
+		for i, stepsBranch := range stepsBranches {
+			queues[i] = make(chan branch, limit)
+			go func(queue chan branch, results chan branchResult) {
+				for {
+					select {
+					case <-opts.Ctx.Done():
+						return
+					case item, isOpen := <-queue:
+						if !isOpen {
+							return
+						}
+						res := branchResult{in: item, value: item}
+						out := recoverStep(func(in StepInput) StepOutput {
+							res.value, res.failed = runBranch(stepsBranch.StepWrappers, item, in.TransformerOptions)
+							return StepOutput{}
+						}, StepInput{TransformerOptions: opts})
+						res.err = out.Error
+						results <- res
+						notifyReady()
+					}
+				}
+			}(queues[i], results)
+		}
+	}
+	stop := func() {
+		for _, queue := range queues {
+			close(queue)
+		}
+		queues = nil
+		inFlight = 0
+		seq = 0
+	}
+	collect := func(ctx context.Context, wait int) StepOutput {
+		out := StepOutput{ArgsLen: 1}
+		for received := 0; inFlight > 0; received++ {
+			var res branchResult
+			if received < wait {
+				select {
+				case <-ctx.Done():
+					out.Error = ctx.Err()
+					return out
+				case res = <-results:
 				}
-				if prevStepOut[i] != inArgTypes[i] {
-					return ArgTypes{}, fmt.Errorf("%w [%s!=%s:%d]", ErrIncompatibleInArgType, prevStepOut[i].String(), inArgTypes[i].String(), i)
+			} else {
+				select {
+				case res = <-results:
+				default:
+					return out
 				}
 			}
-			for _, container := range stepsBranches {
-				if _, _, err := getValidatedSteps[IN0](container.StepWrappers); err != nil {
-					return ArgTypes{}, err
+
+			inFlight--
+			out.MultiArgs = append(out.MultiArgs, res.failed.MultiArgs...)
+			out.MultiErrors = append(out.MultiErrors, res.failed.MultiErrors...)
+			if res.err != nil {
+				// the failed input is followed by a skipped output, so Merge still receives every position of the input order
+				out.MultiArgs = append(out.MultiArgs, Args{res.in.value})
+				out.MultiErrors = append(out.MultiErrors, res.err)
+				res.value.skipped = true
+			}
+			out.MultiArgs = append(out.MultiArgs, Args{res.value})
+			out.MultiErrors = append(out.MultiErrors, nil)
+		}
+		return out
+	}
+
+	return StepWrapper{
+		Name: "WithParallelBranches",
+		StepFn: func(in StepInput) StepOutput {
+			keyVal := in.Args[0].(branch)
+			if keyVal.skipped {
+				return StepOutput{
+					Args:    in.Args,
+					ArgsLen: 1,
 				}
 			}
-			return ArgTypes{branchType}, nil
+			if queues == nil {
+				start(in.TransformerOptions)
+			}
+
+			var wait int
+			if inFlight == limit {
+				wait = 1
+			}
+			out := collect(in.TransformerOptions.Ctx, wait)
+			if out.Error != nil {
+				return out
+			}
+
+			if keyVal.seq == 0 {
+				seq++
+				keyVal.seq = seq
+			}
+			queues[int(keyVal.key)] <- keyVal
+			inFlight++
+
+			if out.MultiArgs == nil {
+				out.Skip = true
+			}
+			return out
+		},
+		Flush: func(opts TransformerOptions) StepOutput {
+			// every result still in flight is collected before the branches are stopped
+			out := collect(opts.Ctx, inFlight)
+			stop()
+			if out.Error != nil {
+				return out
+			}
+			if out.MultiArgs == nil {
+				out.MultiArgs = []Args{}
+			}
+			if hasFlush {
+				flushed := flushBranches(stepsBranches, opts)
+				if flushed.Error != nil {
+					return flushed
+				}
+				out.MultiArgs = append(out.MultiArgs, flushed.MultiArgs...)
+				out.MultiErrors = append(out.MultiErrors, flushed.MultiErrors...)
+			}
+			return out
+		},
+		Ready: func() <-chan struct{} {
+			if inFlight == 0 {
+				return nil
+			}
+			readyMu.Lock()
+			defer readyMu.Unlock()
+			if ready == nil || isClosed(ready) {
+				ready = make(chan struct{})
+				if len(results) > 0 {
+					close(ready)
+				}
+			}
+			return ready
+		},
+		FlushReady: func(opts TransformerOptions) StepOutput {
+			out := collect(opts.Ctx, 0)
+			if out.Error == nil && out.MultiArgs == nil {
+				out.MultiArgs = []Args{}
+			}
+			return out
 		},
+		Validate: branchesValidation[IN0](stepsBranches),
+		Reset: func() {
+			stop()
+			if reset != nil {
+				reset()
+			}
+		},
+	}
+}
+
+// mergedArgs appends the outputs of the branch item to the merged outputs (nothing when the item was skipped)
+func mergedArgs(merged []Args, item branch) []Args {
+	switch {
+	case item.skipped:
+	case item.values != nil:
+		for _, value := range item.values {
+			merged = append(merged, Args{value})
+		}
+	default:
+		merged = append(merged, Args{item.value})
 	}
+	return merged
 }
 
-// Merge merges back the transformation branches
-func Merge() StepWrapper {
+// Merge merges back the transformation branches.
+// The outputs of parallel branches are merged in the input order, unless [CompletionOrder] is given.
+// The buffered outputs are passed as soon as the outputs of the preceding inputs are received
+// (while a channel input is waiting for the next item, when they are passed by [WithParallelBranches]).
+func Merge(order ...MergeOrder) StepWrapper {
+	inCompletionOrder := len(order) > 0 && order[0] == CompletionOrder
+	next := uint64(1)
+	buffered := map[uint64]branch{}
+
 	return StepWrapper{
 		Name: "Merge",
 		StepFn: func(in StepInput) StepOutput {
+			keyVal := in.Args[0].(branch)
+			if keyVal.seq == 0 || inCompletionOrder {
+				if keyVal.values != nil {
+					return StepOutput{
+						MultiArgs: mergedArgs([]Args{}, keyVal),
+						ArgsLen:   1,
+					}
+				}
+				return StepOutput{
+					Args:    Args{keyVal.value},
+					ArgsLen: 1,
+					Skip:    keyVal.skipped,
+				}
+			}
+
+			buffered[keyVal.seq] = keyVal
+			var merged []Args
+			for item, ok := buffered[next]; ok; item, ok = buffered[next] {
+				delete(buffered, next)
+				next++
+				merged = mergedArgs(merged, item)
+			}
+			if merged == nil {
+				return StepOutput{Skip: true}
+			}
 			return StepOutput{
-				Args:    Args{in.Args[0].(branch).value},
-				ArgsLen: 1,
-				Error:   nil,
+				MultiArgs: merged,
+				ArgsLen:   1,
+			}
+		},
+		Flush: func(TransformerOptions) StepOutput {
+			merged := []Args{}
+			for _, seq := range slices.Sorted(maps.Keys(buffered)) {
+				merged = mergedArgs(merged, buffered[seq])
+			}
+			return StepOutput{
+				MultiArgs: merged,
+				ArgsLen:   1,
 			}
 		},
 		Validate: func(prevStepOut ArgTypes) (ArgTypes, error) {
-			inArgTypes := ArgTypes{branchType}
-			for i := range maxArgs {
-				if i == 0 && prevStepOut[0] == reflect.TypeFor[SkipFirstArgValidation]() {
-					continue
-				}
-				if prevStepOut[i] != inArgTypes[i] {
-					return ArgTypes{}, fmt.Errorf("%w [%s!=%s:%d]", ErrIncompatibleInArgType, prevStepOut[i].String(), inArgTypes[i].String(), i+1)
-				}
+			if err := argTypesValidation(prevStepOut, ArgTypes{branchType}); err != nil {
+				return ArgTypes{}, err
 			}
 			return ArgTypes{reflect.TypeFor[any]()}, nil
 		},
+		Reset: func() {
+			next = 1
+			buffered = map[uint64]branch{}
+		},
 	}
 }
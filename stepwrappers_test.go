@@ -2,12 +2,17 @@ package steps
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"iter"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -71,6 +76,567 @@ func TestMap_Validate(t *testing.T) {
 	}
 }
 
+func TestMultiArgMaps_Success(t *testing.T) {
+	actual := Transform[string]([]string{"a1", "b2", "c3"}, WithErrorHandler(expectsError(t, false))).
+		WithSteps(
+			Map1To2(func(in string) (string, string, error) {
+				return in[:1], in[1:], nil
+			}),
+			Map2To2(func(key, value string) (string, int, error) {
+				num, err := strconv.Atoi(value)
+				return strings.ToUpper(key), num, err
+			}),
+			Filter2(func(key string, value int) (bool, error) {
+				return value > 1, nil
+			}),
+			Map2To1(func(key string, value int) (string, error) {
+				return strings.Repeat(key, value), nil
+			}),
+		).
+		AsSlice()
+
+	assert.Equal(t, []any{"BB", "CCC"}, actual)
+}
+
+func TestMultiArgMaps_Failure(t *testing.T) {
+	for _, sc := range []struct {
+		name string
+		step StepWrapper
+	}{
+		{
+			name: "Map2To1",
+			step: Map2To1(func(key string, value int) (string, error) {
+				return "", errors.New("map error")
+			}),
+		}, {
+			name: "Map2To2",
+			step: Map2To2(func(key string, value int) (string, int, error) {
+				return "", 0, errors.New("map error")
+			}),
+		}, {
+			name: "Filter2",
+			step: Filter2(func(key string, value int) (bool, error) {
+				return false, errors.New("map error")
+			}),
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			actual := Transform[string]([]string{"a"}, WithErrorHandler(expectsError(t, true))).
+				WithSteps(
+					Map1To2(func(in string) (string, int, error) {
+						return in, len(in), nil
+					}),
+					sc.step,
+				).
+				AsSlice()
+
+			assert.Empty(t, actual)
+		})
+	}
+
+	actual := Transform[string]([]string{"a"}, WithErrorHandler(expectsError(t, true))).
+		WithSteps(
+			Map1To2(func(in string) (string, int, error) {
+				return "", 0, errors.New("map error")
+			}),
+		).
+		AsSlice()
+	assert.Empty(t, actual)
+}
+
+func TestMultiArgMaps_Validate(t *testing.T) {
+	stringType, intType := reflect.TypeFor[string](), reflect.TypeFor[int]()
+	for _, sc := range []struct {
+		name          string
+		step          StepWrapper
+		prevStepOut   ArgTypes
+		expectedOut   ArgTypes
+		expectedError string
+	}{
+		{
+			name:        "Map1To2_matching_prev_step_out_types",
+			step:        Map1To2(func(in string) (string, int, error) { return "", 0, nil }),
+			prevStepOut: ArgTypes{stringType},
+			expectedOut: ArgTypes{stringType, intType},
+		}, {
+			name:        "Map1To2_skip_type_check_when_first_step",
+			step:        Map1To2(func(in string) (string, int, error) { return "", 0, nil }),
+			prevStepOut: ArgTypes{reflect.TypeFor[SkipFirstArgValidation]()},
+			expectedOut: ArgTypes{stringType, intType},
+		}, {
+			name:          "Map1To2_more_prev_step_out_types",
+			step:          Map1To2(func(in string) (string, int, error) { return "", 0, nil }),
+			prevStepOut:   ArgTypes{stringType, intType},
+			expectedError: "[int!=<nil>:2]",
+		}, {
+			name:        "Map2To1_matching_prev_step_out_types",
+			step:        Map2To1(func(in0 string, in1 int) (bool, error) { return false, nil }),
+			prevStepOut: ArgTypes{stringType, intType},
+			expectedOut: ArgTypes{reflect.TypeFor[bool]()},
+		}, {
+			name:          "Map2To1_different_second_prev_step_out_type",
+			step:          Map2To1(func(in0 string, in1 int) (bool, error) { return false, nil }),
+			prevStepOut:   ArgTypes{stringType, stringType},
+			expectedError: "[string!=int:2]",
+		}, {
+			name:          "Map2To1_missing_second_arg_when_first_step",
+			step:          Map2To1(func(in0 string, in1 int) (bool, error) { return false, nil }),
+			prevStepOut:   ArgTypes{reflect.TypeFor[SkipFirstArgValidation]()},
+			expectedError: "[<nil>!=int:2]",
+		}, {
+			name:        "Map2To2_matching_prev_step_out_types",
+			step:        Map2To2(func(in0 string, in1 int) (int, string, error) { return 0, "", nil }),
+			prevStepOut: ArgTypes{stringType, intType},
+			expectedOut: ArgTypes{intType, stringType},
+		}, {
+			name:          "Map2To2_different_first_prev_step_out_type",
+			step:          Map2To2(func(in0 string, in1 int) (int, string, error) { return 0, "", nil }),
+			prevStepOut:   ArgTypes{intType, intType},
+			expectedError: "[int!=string:1]",
+		}, {
+			name:        "Filter2_matching_prev_step_out_types",
+			step:        Filter2(func(in0 string, in1 int) (bool, error) { return false, nil }),
+			prevStepOut: ArgTypes{stringType, intType},
+			expectedOut: ArgTypes{stringType, intType},
+		}, {
+			name:          "Filter2_single_prev_step_out_type",
+			step:          Filter2(func(in0 string, in1 int) (bool, error) { return false, nil }),
+			prevStepOut:   ArgTypes{stringType},
+			expectedError: "[<nil>!=int:2]",
+		}, {
+			name:          "Map_after_multiple_outputs",
+			step:          Map(func(in string) (int, error) { return 0, nil }),
+			prevStepOut:   ArgTypes{stringType, intType},
+			expectedError: "[int!=<nil>:2]",
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			actualOut, actualErr := sc.step.Validate(sc.prevStepOut)
+
+			assert.Equal(t, sc.expectedOut, actualOut)
+			if len(sc.expectedError) > 0 {
+				assert.ErrorIs(t, actualErr, ErrIncompatibleInArgType)
+				assert.ErrorContains(t, actualErr, sc.expectedError)
+			} else {
+				assert.NoError(t, actualErr)
+			}
+		})
+	}
+}
+
+func TestParallelMap_Success(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	actual := Transform[int]([]int{1, 2, 3, 4, 5, 6, 7}, WithErrorHandler(expectsError(t, false))).
+		WithSteps(
+			ParallelMap(3, func(in int) (string, error) {
+				maxRunning.Store(max(maxRunning.Load(), running.Add(1)))
+				defer running.Add(-1)
+				time.Sleep(time.Duration(10-in) * time.Millisecond)
+				return strconv.Itoa(in * 10), nil
+			}),
+			Take[string](6)).
+		AsSlice()
+
+	assert.Equal(t, []any{"10", "20", "30", "40", "50", "60"}, actual)
+	assert.LessOrEqual(t, maxRunning.Load(), int32(3))
+	assert.Greater(t, maxRunning.Load(), int32(1))
+}
+
+func TestParallelMap_Failure(t *testing.T) {
+	actual := Transform[string]([]string{"1", "2", "x", "4", "5"}, WithErrorHandler(expectsError(t, true))).
+		WithSteps(
+			ParallelMap(2, func(in string) (int, error) {
+				return strconv.Atoi(in)
+			})).
+		AsSlice()
+
+	assert.Equal(t, []any{1, 2}, actual)
+}
+
+func TestParallelMap_ErrorPolicy(t *testing.T) {
+	errTwo := errors.New("input 2 failed")
+	for _, sc := range []struct {
+		name               string
+		workers            int
+		policy             ErrorPolicy
+		expected           []any
+		expectedErrs       []error
+		expectedDeadLetter []FailedItem
+	}{
+		{
+			name:         "stop",
+			workers:      2,
+			policy:       Stop,
+			expected:     []any{10},
+			expectedErrs: []error{errTwo},
+		}, {
+			name:         "continue",
+			workers:      2,
+			policy:       Continue,
+			expected:     []any{10, 30, 40, 50, 60},
+			expectedErrs: []error{errTwo, errTwo},
+		}, {
+			name:         "continue_flushed",
+			workers:      10,
+			policy:       Continue,
+			expected:     []any{10, 30, 40, 50, 60},
+			expectedErrs: []error{errTwo, errTwo},
+		}, {
+			name:     "dead_letter",
+			workers:  2,
+			policy:   DeadLetter,
+			expected: []any{10, 30, 40, 50, 60},
+			expectedDeadLetter: []FailedItem{
+				{Error: errTwo, Step: "ParallelMap", Position: 1, Args: Args{2}, ArgsLen: 1},
+				{Error: errTwo, Step: "ParallelMap", Position: 1, Args: Args{2}, ArgsLen: 1},
+			},
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			var actualErrs []error
+			var deadLetter []FailedItem
+			actual := Transform[int]([]int{1, 2, 3, 4, 5, 2, 6},
+				WithErrorPolicy(sc.policy),
+				WithErrorHandler(func(err error) {
+					actualErrs = append(actualErrs, err)
+				}),
+				WithDeadLetter(func(item FailedItem) {
+					deadLetter = append(deadLetter, item)
+				})).
+				WithSteps(
+					ParallelMap(sc.workers, func(in int) (int, error) {
+						if in == 2 {
+							return 0, errTwo
+						}
+						return in * 10, nil
+					})).
+				AsSlice()
+
+			assert.Equal(t, sc.expected, actual)
+			assert.Equal(t, sc.expectedErrs, actualErrs)
+			assert.Equal(t, sc.expectedDeadLetter, deadLetter)
+		})
+	}
+}
+
+func TestParallelMap_Panics(t *testing.T) {
+	var actualErr error
+	actual := Transform[int]([]int{1, 2, 0, 4}, WithErrorHandler(func(err error) {
+		actualErr = err
+	})).
+		WithSteps(
+			Filter(func(in int) (bool, error) {
+				return true, nil
+			}),
+			ParallelMap(2, func(in int) (int, error) {
+				return 10 / in, nil
+			})).
+		AsSlice()
+
+	assert.Equal(t, []any{10, 5}, actual)
+	assert.ErrorIs(t, actualErr, ErrStepPanicked)
+	assert.ErrorContains(t, actualErr, "step panicked [ParallelMap:2]: runtime error: integer divide by zero")
+	var panicErr *StepPanicError
+	if assert.ErrorAs(t, actualErr, &panicErr) {
+		assert.Contains(t, string(panicErr.Stack), "stepwrappers_test.go")
+	}
+}
+
+func TestParallelMap_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+
+	var actualErr error
+	actual := Transform[int]([]int{1, 2, 3}, WithContext(ctx), WithErrorHandler(func(err error) {
+		actualErr = err
+	})).
+		WithSteps(
+			ParallelMap(1, func(in int) (int, error) {
+				if in == 1 {
+					cancel()
+					<-release
+				}
+				return in, nil
+			})).
+		AsSlice()
+
+	assert.Empty(t, actual)
+	assert.ErrorIs(t, actualErr, context.Canceled)
+}
+
+func TestParallelMap_IdleInput(t *testing.T) {
+	inputCh := make(chan int)
+	release := make(chan struct{})
+	outputs := make(chan any, 3)
+	go func() {
+		defer close(outputs)
+		for out := range Transform[int](inputCh, WithErrorHandler(expectsError(t, false))).
+			WithSteps(ParallelMap(3, func(in int) (int, error) {
+				if in == 2 {
+					<-release
+				}
+				return in * 10, nil
+			})).
+			AsRange() {
+			outputs <- out
+		}
+	}()
+
+	expectOutput := func(expected any, msg string) {
+		select {
+		case actual := <-outputs:
+			assert.Equal(t, expected, actual)
+		case <-time.After(time.Second):
+			assert.Fail(t, msg)
+		}
+	}
+	inputCh <- 1
+	inputCh <- 2
+	expectOutput(10, "completed output is not emitted while the input is idle")
+	close(release)
+	expectOutput(20, "released output is not emitted while the input is idle")
+
+	inputCh <- 3
+	close(inputCh)
+	var actual []any
+	for out := range outputs {
+		actual = append(actual, out)
+	}
+	assert.Equal(t, []any{30}, actual)
+}
+
+func TestParallelMap_IdleInputMultipleSteps(t *testing.T) {
+	waitFor := func(blocked int, release chan struct{}) StepWrapper {
+		return ParallelMap(3, func(in int) (int, error) {
+			if in == blocked {
+				<-release
+			}
+			return in, nil
+		})
+	}
+	inputCh := make(chan int)
+	releaseFirst, releaseSecond := make(chan struct{}), make(chan struct{})
+	outputs := make(chan any, 2)
+	go func() {
+		defer close(outputs)
+		for out := range Transform[int](inputCh, WithErrorHandler(expectsError(t, false))).
+			WithSteps(waitFor(2, releaseFirst), waitFor(1, releaseSecond)).
+			AsRange() {
+			outputs <- out
+		}
+	}()
+
+	expectOutput := func(expected any) {
+		select {
+		case actual := <-outputs:
+			assert.Equal(t, expected, actual)
+		case <-time.After(time.Second):
+			assert.Fail(t, "completed output is not emitted while the input is idle")
+		}
+	}
+	// both steps are waiting for an output, so the transformer waits for any of them
+	inputCh <- 1
+	inputCh <- 2
+	close(releaseSecond)
+	expectOutput(1)
+	close(releaseFirst)
+	expectOutput(2)
+
+	close(inputCh)
+	for out := range outputs {
+		assert.Fail(t, "unexpected output", out)
+	}
+}
+
+func TestParallelMap_Validate(t *testing.T) {
+	for _, sc := range []struct {
+		name         string
+		prevStepOut  ArgTypes
+		expectedOut  ArgTypes
+		expectsError bool
+	}{
+		{
+			name:        "matching_prev_step_out_type",
+			prevStepOut: ArgTypes{reflect.TypeFor[string]()},
+			expectedOut: ArgTypes{reflect.TypeFor[int]()},
+		}, {
+			name:         "different_prev_step_out_type",
+			prevStepOut:  ArgTypes{reflect.TypeFor[int]()},
+			expectsError: true,
+		}, {
+			name:        "skip_type_check_when_first_step",
+			prevStepOut: ArgTypes{reflect.TypeFor[SkipFirstArgValidation]()},
+			expectedOut: ArgTypes{reflect.TypeFor[int]()},
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			actualOut, actualErr := ParallelMap(2, func(in string) (int, error) {
+				return 0, nil
+			}).Validate(sc.prevStepOut)
+
+			assert.Equal(t, sc.expectedOut, actualOut)
+			if sc.expectsError {
+				assert.ErrorIs(t, actualErr, ErrIncompatibleInArgType)
+				assert.ErrorContains(t, actualErr, "[int!=string:1]")
+			} else {
+				assert.NoError(t, actualErr)
+			}
+		})
+	}
+}
+
+func TestFlatMap_Success(t *testing.T) {
+	actual := Transform[string]([]string{"1,2", "", "3"}, WithErrorHandler(expectsError(t, false))).
+		WithSteps(
+			FlatMap(func(in string) ([]string, error) {
+				if len(in) == 0 {
+					return nil, nil
+				}
+				return strings.Split(in, ","), nil
+			}),
+			Map(func(in string) (int, error) {
+				return strconv.Atoi(in)
+			})).
+		AsSlice()
+
+	assert.Equal(t, []any{1, 2, 3}, actual)
+}
+
+func TestFlatMap_Aggregated(t *testing.T) {
+	actual := Transform[int]([]int{1, 2, 3}, WithErrorHandler(expectsError(t, false))).
+		With(Steps(
+			FlatMap(func(in int) ([]int, error) {
+				return []int{in, in * 10}, nil
+			}),
+		).Aggregate(Sum[int]())).
+		AsSlice()
+
+	assert.Equal(t, []any{66}, actual)
+}
+
+func TestFlatMap_Failure(t *testing.T) {
+	actual := Transform[string]([]string{"1,2", "3,x", "4"}, WithErrorHandler(expectsError(t, true))).
+		WithSteps(
+			FlatMap(func(in string) ([]string, error) {
+				return strings.Split(in, ","), nil
+			}),
+			Map(func(in string) (int, error) {
+				return strconv.Atoi(in)
+			})).
+		AsSlice()
+
+	assert.Equal(t, []any{1, 2, 3}, actual)
+}
+
+func TestFlatMap_OutputsDiscardedOnFailure(t *testing.T) {
+	var actualErrs []error
+	actual := Transform[int]([]int{1, 2, 3}, WithErrorPolicy(Continue), WithErrorHandler(func(err error) {
+		actualErrs = append(actualErrs, err)
+	})).
+		WithSteps(
+			FlatMap(func(in int) ([]int, error) {
+				if in == 2 {
+					return []int{20, 21}, errors.New("flatmap error")
+				}
+				return []int{in}, nil
+			})).
+		AsSlice()
+
+	assert.Equal(t, []any{1, 3}, actual)
+	assert.Len(t, actualErrs, 1)
+}
+
+func TestFlatMap_Validate(t *testing.T) {
+	for _, stepWrapper := range []StepWrapper{
+		FlatMap(func(in string) ([]int, error) {
+			return nil, nil
+		}),
+		FlatMapSeq(func(in string) (iter.Seq[int], error) {
+			return nil, nil
+		}),
+	} {
+		t.Run(stepWrapper.Name, func(t *testing.T) {
+			actualOut, actualErr := stepWrapper.Validate(ArgTypes{reflect.TypeFor[string]()})
+			assert.Equal(t, ArgTypes{reflect.TypeFor[int]()}, actualOut)
+			assert.NoError(t, actualErr)
+
+			actualOut, actualErr = stepWrapper.Validate(ArgTypes{reflect.TypeFor[SkipFirstArgValidation]()})
+			assert.Equal(t, ArgTypes{reflect.TypeFor[int]()}, actualOut)
+			assert.NoError(t, actualErr)
+
+			_, actualErr = stepWrapper.Validate(ArgTypes{reflect.TypeFor[int]()})
+			assert.ErrorIs(t, actualErr, ErrIncompatibleInArgType)
+			assert.ErrorContains(t, actualErr, "[int!=string:1]")
+		})
+	}
+}
+
+func TestFlatMapSeq_Success(t *testing.T) {
+	actual := Transform[int]([]int{3, 0, 2}, WithErrorHandler(expectsError(t, false))).
+		WithSteps(
+			FlatMapSeq(func(in int) (iter.Seq[int], error) {
+				return func(yield func(int) bool) {
+					for i := range in {
+						if !yield(in*10 + i) {
+							return
+						}
+					}
+				}, nil
+			})).
+		AsSlice()
+
+	assert.Equal(t, []any{30, 31, 32, 20, 21}, actual)
+}
+
+func TestFlatMapSeq_Failure(t *testing.T) {
+	actual := Transform[int]([]int{1, 2, 3}, WithErrorHandler(expectsError(t, true))).
+		WithSteps(
+			FlatMapSeq(func(in int) (iter.Seq[int], error) {
+				if in == 2 {
+					return nil, errors.New("flatmap error")
+				}
+				return slices.Values([]int{in, in}), nil
+			})).
+		AsSlice()
+
+	assert.Equal(t, []any{1, 1}, actual)
+}
+
+func TestFlatMapSeq_InfiniteSequence(t *testing.T) {
+	naturals := FlatMapSeq(func(in int) (iter.Seq[int], error) {
+		return func(yield func(int) bool) {
+			for i := in; ; i++ {
+				if !yield(i) {
+					return
+				}
+			}
+		}, nil
+	})
+
+	t.Run("stopped_by_take", func(t *testing.T) {
+		actual := Transform[int]([]int{1, 100}, WithErrorHandler(expectsError(t, false))).
+			WithSteps(naturals, Take[int](3)).
+			AsSlice()
+
+		assert.Equal(t, []any{1, 2, 3}, actual)
+	})
+
+	t.Run("stopped_by_consumer", func(t *testing.T) {
+		var actual []any
+		for out := range Transform[int]([]int{1}, WithErrorHandler(expectsError(t, false))).
+			WithSteps(naturals).
+			AsRange() {
+			if len(actual) == 2 {
+				break
+			}
+			actual = append(actual, out)
+		}
+
+		assert.Equal(t, []any{1, 2}, actual)
+	})
+}
+
 func testSimpleFilterValidate(t *testing.T, stepWrapper StepWrapper) {
 	t.Helper()
 	for _, sc := range []struct {
@@ -220,6 +786,192 @@ func TestSkipWhile_Validate(t *testing.T) {
 	testSimpleFilterValidate(t, Skip[int](0))
 }
 
+func TestBatch_Success(t *testing.T) {
+	for _, sc := range []struct {
+		name     string
+		size     int
+		input    []int
+		expected []any
+	}{
+		{
+			name:     "full_batches",
+			size:     2,
+			input:    []int{1, 2, 3, 4},
+			expected: []any{[]int{1, 2}, []int{3, 4}},
+		}, {
+			name:     "partial_last_batch_flushed",
+			size:     3,
+			input:    []int{1, 2, 3, 4},
+			expected: []any{[]int{1, 2, 3}, []int{4}},
+		}, {
+			name:     "without_size_limit",
+			input:    []int{1, 2, 3, 4},
+			expected: []any{[]int{1, 2, 3, 4}},
+		}, {
+			name:  "empty_input",
+			size:  2,
+			input: []int{},
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			var actual []any
+			for batch := range Transform[int](sc.input, WithErrorHandler(expectsError(t, false))).
+				WithSteps(Batch[int](sc.size, 0)).
+				AsRange() {
+				actual = append(actual, batch)
+			}
+
+			assert.Equal(t, sc.expected, actual)
+		})
+	}
+}
+
+func TestBatch_MaxWait(t *testing.T) {
+	inputCh := make(chan int)
+	go func() {
+		for _, v := range []int{1, 2, 3, 4, 5, 6} {
+			// the batch of the first 3 items is full, the 4th item starts a new batch which is emitted on time
+			if v == 5 {
+				time.Sleep(50 * time.Millisecond)
+			}
+			inputCh <- v
+		}
+		close(inputCh)
+	}()
+
+	actual := Transform[int](inputCh, WithErrorHandler(expectsError(t, false))).
+		WithSteps(Batch[int](3, 10*time.Millisecond)).
+		AsSlice()
+
+	assert.Equal(t, []any{[]int{1, 2, 3}, []int{4}, []int{5, 6}}, actual)
+}
+
+func TestBatch_MaxWaitIdleInput(t *testing.T) {
+	inputCh := make(chan int)
+	batches := make(chan any)
+	go func() {
+		defer close(batches)
+		for batch := range Transform[int](inputCh, WithErrorHandler(expectsError(t, false))).
+			WithSteps(Batch[int](10, 10*time.Millisecond)).
+			AsRange() {
+			batches <- batch
+		}
+	}()
+
+	inputCh <- 1
+	inputCh <- 2
+	select {
+	case actual := <-batches:
+		assert.Equal(t, []int{1, 2}, actual)
+	case <-time.After(time.Second):
+		assert.Fail(t, "batch is not emitted while the input is idle")
+	}
+
+	inputCh <- 3
+	close(inputCh)
+	var actual []any
+	for batch := range batches {
+		actual = append(actual, batch)
+	}
+	assert.Equal(t, []any{[]int{3}}, actual)
+}
+
+func TestBatch_Validate(t *testing.T) {
+	actualOut, actualErr := Batch[int](2, 0).Validate(ArgTypes{reflect.TypeFor[int]()})
+	assert.Equal(t, ArgTypes{reflect.TypeFor[[]int]()}, actualOut)
+	assert.NoError(t, actualErr)
+
+	_, actualErr = Batch[int](2, 0).Validate(ArgTypes{reflect.TypeFor[string]()})
+	assert.ErrorIs(t, actualErr, ErrIncompatibleInArgType)
+	assert.ErrorContains(t, actualErr, "[string!=int:1]")
+
+	_, actualErr = Batch[int](-1, 0).Validate(ArgTypes{reflect.TypeFor[int]()})
+	assert.ErrorIs(t, actualErr, ErrInvalidStepConfig)
+	assert.EqualError(t, actualErr, "invalid step configuration [size:-1]")
+
+	var panicked bool
+	actual, err := Transform[int]([]int{1, 2}, WithPanicHandler(func(error) {
+		panicked = true
+	})).
+		WithSteps(Batch[int](-1, 0)).
+		AsSliceE()
+	assert.Empty(t, actual)
+	assert.ErrorIs(t, err, ErrInvalidStepConfig)
+	assert.NotErrorIs(t, err, ErrStepPanicked)
+	assert.False(t, panicked)
+}
+
+func TestBatch_ErrorPolicy(t *testing.T) {
+	errBatch := errors.New("batch failed")
+	for _, sc := range []struct {
+		name               string
+		policy             ErrorPolicy
+		expected           []any
+		expectedErrs       []error
+		expectedDeadLetter []FailedItem
+	}{
+		{
+			name:         "stop",
+			policy:       Stop,
+			expected:     []any{3},
+			expectedErrs: []error{errBatch},
+		}, {
+			name:         "continue",
+			policy:       Continue,
+			expected:     []any{3, 11, 14},
+			expectedErrs: []error{errBatch},
+		}, {
+			name:     "dead_letter",
+			policy:   DeadLetter,
+			expected: []any{3, 11, 14},
+			expectedDeadLetter: []FailedItem{
+				{Error: errBatch, Step: "Map", Position: 2, Args: Args{[]int{3, 4}}, ArgsLen: 1},
+			},
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			var actualErrs []error
+			var deadLetter []FailedItem
+			actual := Transform[int]([]int{1, 2, 3, 4, 5, 6, 7},
+				WithErrorPolicy(sc.policy),
+				WithErrorHandler(func(err error) {
+					actualErrs = append(actualErrs, err)
+				}),
+				WithDeadLetter(func(item FailedItem) {
+					deadLetter = append(deadLetter, item)
+				})).
+				WithSteps(
+					Batch[int](2, 0),
+					Map(func(in []int) (int, error) {
+						if slices.Contains(in, 3) {
+							return 0, errBatch
+						}
+						return in[0] + in[len(in)-1], nil
+					})).
+				AsSlice()
+
+			assert.Equal(t, sc.expected, actual)
+			assert.Equal(t, sc.expectedErrs, actualErrs)
+			assert.Equal(t, sc.expectedDeadLetter, deadLetter)
+		})
+	}
+
+	t.Run("failed_input_not_batched", func(t *testing.T) {
+		actual := Transform[int]([]int{1, 2, 3, 4, 5}, WithErrorPolicy(Continue), WithErrorHandler(expectsError(t, true))).
+			WithSteps(
+				Filter(func(in int) (bool, error) {
+					if in == 3 {
+						return false, errBatch
+					}
+					return true, nil
+				}),
+				Batch[int](2, 0)).
+			AsSlice()
+
+		assert.Equal(t, []any{[]int{1, 2}, []int{4, 5}}, actual)
+	})
+}
+
 type testLogWriter struct {
 	output      []byte
 	returnError error
@@ -485,8 +1237,40 @@ func TestWithBranches_Success(t *testing.T) {
 			Merge()).
 		AsSlice()
 
-	assert.EqualValues(t, []any{2, 3, 6, 5}, actual)
-	assert.True(t, bytes.HasPrefix(testLogWriter.output, []byte("test")))
+	assert.EqualValues(t, []any{2, 3, 6, 5}, actual)
+	assert.True(t, bytes.HasPrefix(testLogWriter.output, []byte("test")))
+}
+
+func TestWithBranches_MultipleOutputsAndFlush(t *testing.T) {
+	split := Split(func(in int) (uint8, error) {
+		return uint8(in % 2), nil
+	})
+	repeat := FlatMap(func(in int) ([]int, error) {
+		return []int{in, in}, nil
+	})
+	repeatSeq := FlatMapSeq(func(in int) (iter.Seq[int], error) {
+		return slices.Values([]int{in, in * 10}), nil
+	})
+	sumBatch := Map(func(in []int) (int, error) {
+		var sum int
+		for _, v := range in {
+			sum += v
+		}
+		return sum, nil
+	})
+
+	transformer := Transform[int]([]int{1, 2, 3, 4, 5}, WithErrorHandler(expectsError(t, false))).
+		WithSteps(split,
+			WithBranches[int](
+				Steps(repeat, Batch[int](3, 0), sumBatch),
+				Steps(repeatSeq, Take[int](3)),
+			),
+			Merge())
+
+	// the branch states are reset for each run
+	for range 2 {
+		assert.Equal(t, []any{1, 10, 3, 8, 4}, transformer.AsSlice())
+	}
 }
 
 func TestWithBranches_Failure(t *testing.T) {
@@ -515,6 +1299,39 @@ func TestWithBranches_Failure(t *testing.T) {
 	assert.EqualValues(t, []any{2, 3, 6}, actual)
 }
 
+func TestWithBranches_DeadLetter(t *testing.T) {
+	errFailed := errors.New("branch failed")
+	var deadLetter []FailedItem
+	actual := Transform[int]([]int{1, 2, 3, 4}, WithErrorPolicy(DeadLetter), WithDeadLetter(func(item FailedItem) {
+		deadLetter = append(deadLetter, item)
+	})).
+		WithSteps(
+			Split(func(in int) (uint8, error) {
+				return uint8(in % 2), nil
+			}),
+			WithBranches[int](
+				Steps(Map(func(in int) (int, error) {
+					if in == 4 {
+						return 0, errFailed
+					}
+					return in + 1, nil
+				})),
+				Steps(Map(func(in int) (int, error) {
+					return in * 2, nil
+				})),
+			),
+			Merge()).
+		AsSlice()
+
+	assert.Equal(t, []any{2, 3, 6}, actual)
+	if assert.Len(t, deadLetter, 1) {
+		assert.Equal(t, "WithBranches", deadLetter[0].Step)
+		assert.ErrorIs(t, deadLetter[0].Error, errFailed)
+		assert.IsType(t, 0, deadLetter[0].Args[0])
+		assert.Equal(t, 4, deadLetter[0].Args[0])
+	}
+}
+
 func TestWithBranches_Validate(t *testing.T) {
 	for _, sc := range []struct {
 		name                  string
@@ -531,7 +1348,7 @@ func TestWithBranches_Validate(t *testing.T) {
 		}, {
 			name:                "only_branch_type_as_prev_step_out",
 			prevStepOut:         ArgTypes{reflect.TypeFor[string]()},
-			expectsErrorContain: "[string!=steps.branch:",
+			expectsErrorContain: "[string!=steps.branch:1]",
 		}, {
 			name:        "skip_type_check_when_first_step",
 			prevStepOut: ArgTypes{reflect.TypeFor[SkipFirstArgValidation]()},
@@ -602,6 +1419,323 @@ func TestWithBranches_Validate(t *testing.T) {
 	}
 }
 
+func TestWithParallelBranches_Success(t *testing.T) {
+	split := Split(func(in int) (uint8, error) {
+		return uint8(in % 2), nil
+	})
+	slowAddOne := Map(func(in int) (int, error) {
+		time.Sleep(5 * time.Millisecond)
+		return in + 1, nil
+	})
+	multiplyByTen := Map(func(in int) (int, error) {
+		return in * 10, nil
+	})
+	dropForty := Filter(func(in int) (bool, error) {
+		return in != 40, nil
+	})
+
+	for _, sc := range []struct {
+		name     string
+		order    []MergeOrder
+		expected []any
+	}{
+		{
+			name:     "merged_in_input_order",
+			expected: []any{2, 20, 4, 6},
+		}, {
+			name:     "merged_in_input_order_explicitly",
+			order:    []MergeOrder{InputOrder},
+			expected: []any{2, 20, 4, 6},
+		}, {
+			name:     "merged_in_completion_order",
+			order:    []MergeOrder{CompletionOrder},
+			expected: []any{20, 2, 4, 6},
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			actual := Transform[int]([]int{1, 2, 3, 4, 5}, WithErrorHandler(expectsError(t, false))).
+				WithSteps(split,
+					WithParallelBranches[int](
+						Steps(multiplyByTen, dropForty),
+						Steps(slowAddOne),
+					),
+					Merge(sc.order...)).
+				AsSlice()
+
+			assert.Equal(t, sc.expected, actual)
+		})
+	}
+}
+
+func TestWithParallelBranches_MultipleOutputsAndFlush(t *testing.T) {
+	split := Split(func(in int) (uint8, error) {
+		return uint8(in % 2), nil
+	})
+	slowRepeat := FlatMap(func(in int) ([]int, error) {
+		time.Sleep(time.Duration(5-in) * time.Millisecond)
+		return []int{in, in * 10}, nil
+	})
+	sumBatch := Map(func(in []int) (int, error) {
+		var sum int
+		for _, v := range in {
+			sum += v
+		}
+		return sum, nil
+	})
+
+	actual := Transform[int]([]int{1, 2, 3, 4, 5}, WithChanSize(5), WithErrorHandler(expectsError(t, false))).
+		WithSteps(split,
+			WithParallelBranches[int](
+				Steps(Batch[int](3, 0), sumBatch),
+				Steps(slowRepeat),
+			),
+			Merge()).
+		AsSlice()
+
+	assert.Equal(t, []any{1, 10, 3, 30, 5, 50, 6}, actual)
+}
+
+func TestWithParallelBranches_IdleInput(t *testing.T) {
+	split := Split(func(in int) (uint8, error) {
+		return uint8(in % 2), nil
+	})
+	release := make(chan struct{})
+	waitForRelease := Map(func(in int) (int, error) {
+		<-release
+		return in, nil
+	})
+	multiplyByTen := Map(func(in int) (int, error) {
+		return in * 10, nil
+	})
+
+	inputCh := make(chan int)
+	outputs := make(chan any, 4)
+	go func() {
+		defer close(outputs)
+		for out := range Transform[int](inputCh, WithErrorHandler(expectsError(t, false))).
+			WithSteps(split,
+				WithParallelBranches[int](
+					Steps(waitForRelease),
+					Steps(multiplyByTen),
+				),
+				Merge()).
+			AsRange() {
+			outputs <- out
+		}
+	}()
+
+	expectOutputs := func(expected []any, msg string) {
+		var actual []any
+		for range expected {
+			select {
+			case out := <-outputs:
+				actual = append(actual, out)
+			case <-time.After(time.Second):
+				assert.Fail(t, msg)
+				return
+			}
+		}
+		assert.Equal(t, expected, actual)
+	}
+	inputCh <- 1
+	expectOutputs([]any{10}, "completed branch output is not merged while the input is idle")
+	inputCh <- 2
+	inputCh <- 3
+	close(release)
+	expectOutputs([]any{2, 30}, "buffered branch outputs are not merged while the input is idle")
+
+	inputCh <- 4
+	close(inputCh)
+	var actual []any
+	for out := range outputs {
+		actual = append(actual, out)
+	}
+	assert.Equal(t, []any{4}, actual)
+}
+
+func TestWithParallelBranches_Failure(t *testing.T) {
+	split := Split(func(in int) (uint8, error) {
+		return uint8(in % 2), nil
+	})
+	addOne := Map(func(in int) (int, error) {
+		if in == 4 {
+			return 0, errors.New("add error")
+		}
+		return in + 1, nil
+	})
+	multiplyByTwo := Map(func(in int) (int, error) {
+		return in * 2, nil
+	})
+
+	actual := Transform[int]([]int{1, 2, 3, 4, 5}, WithChanSize(1), WithErrorHandler(expectsError(t, true))).
+		WithSteps(split,
+			WithParallelBranches[int](
+				Steps(addOne),
+				Steps(multiplyByTwo),
+			),
+			Merge()).
+		AsSlice()
+
+	assert.Equal(t, []any{2, 3, 6}, actual)
+}
+
+func TestWithParallelBranches_ErrorPolicy(t *testing.T) {
+	errFailed := errors.New("branch failed")
+	for _, sc := range []struct {
+		name         string
+		failed       int
+		options      []func(*TransformerOptions)
+		expected     []any
+		expectedErrs int
+	}{
+		{
+			name:         "continue",
+			failed:       2,
+			options:      []func(*TransformerOptions){WithErrorPolicy(Continue)},
+			expected:     []any{10, 30, 40, 50, 60},
+			expectedErrs: 1,
+		}, {
+			name:         "continue_when_first_failed",
+			failed:       1,
+			options:      []func(*TransformerOptions){WithErrorPolicy(Continue)},
+			expected:     []any{20, 30, 40, 50, 60},
+			expectedErrs: 1,
+		}, {
+			name:         "continue_with_one_in_flight",
+			failed:       2,
+			options:      []func(*TransformerOptions){WithErrorPolicy(Continue), WithChanSize(1)},
+			expected:     []any{10, 30, 40, 50, 60},
+			expectedErrs: 1,
+		}, {
+			name:         "stop",
+			failed:       2,
+			options:      []func(*TransformerOptions){WithErrorPolicy(Stop), WithChanSize(1)},
+			expected:     []any{10},
+			expectedErrs: 1,
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			multiplyByTen := Map(func(in int) (int, error) {
+				if in == sc.failed {
+					return 0, errFailed
+				}
+				return in * 10, nil
+			})
+			var actualErrs []error
+			options := append(sc.options, WithErrorHandler(func(err error) {
+				actualErrs = append(actualErrs, err)
+			}))
+
+			actual := Transform[int]([]int{1, 2, 3, 4, 5, 6}, options...).
+				WithSteps(
+					Split(func(in int) (uint8, error) {
+						return uint8(in % 2), nil
+					}),
+					WithParallelBranches[int](
+						Steps(multiplyByTen),
+						Steps(multiplyByTen),
+					),
+					Merge()).
+				AsSlice()
+
+			assert.Equal(t, sc.expected, actual)
+			assert.Len(t, actualErrs, sc.expectedErrs)
+			for _, err := range actualErrs {
+				assert.ErrorIs(t, err, errFailed)
+			}
+		})
+	}
+
+	t.Run("dead_letter", func(t *testing.T) {
+		var deadLetter []FailedItem
+		actual := Transform[int]([]int{1, 2, 3}, WithErrorPolicy(DeadLetter), WithDeadLetter(func(item FailedItem) {
+			deadLetter = append(deadLetter, item)
+		})).
+			WithSteps(
+				Split(func(in int) (uint8, error) {
+					return 0, nil
+				}),
+				WithParallelBranches[int](
+					Steps(Map(func(in int) (int, error) {
+						if in == 2 {
+							return 0, errFailed
+						}
+						return in, nil
+					})),
+				),
+				Merge()).
+			AsSlice()
+
+		assert.Equal(t, []any{1, 3}, actual)
+		if assert.Len(t, deadLetter, 1) {
+			assert.Equal(t, "WithParallelBranches", deadLetter[0].Step)
+			assert.IsType(t, 0, deadLetter[0].Args[0])
+			assert.Equal(t, 2, deadLetter[0].Args[0])
+		}
+	})
+}
+
+func TestWithParallelBranches_Panics(t *testing.T) {
+	var actualErr error
+	Transform[int]([]int{1, 2, 3}, WithErrorHandler(func(err error) {
+		actualErr = err
+	})).
+		WithSteps(
+			Split(func(in int) (uint8, error) {
+				return uint8(in % 2), nil
+			}),
+			WithParallelBranches[int](
+				Steps(Map(func(in int) (int, error) {
+					panic("even branch failed")
+				})),
+				Steps(),
+			),
+			Merge(),
+		).
+		AsSlice()
+
+	assert.ErrorIs(t, actualErr, ErrStepPanicked)
+	assert.ErrorContains(t, actualErr, "step panicked [WithParallelBranches:2]: even branch failed")
+}
+
+func TestWithParallelBranches_Validate(t *testing.T) {
+	addOne := Map(func(in int) (int, error) {
+		return in + 1, nil
+	})
+	toString := Map(func(in string) (string, error) {
+		return in, nil
+	})
+
+	actualOut, actualErr := WithParallelBranches[int](Steps(addOne), Steps(addOne)).
+		Validate(ArgTypes{reflect.TypeFor[branch]()})
+	assert.Equal(t, ArgTypes{reflect.TypeFor[branch]()}, actualOut)
+	assert.NoError(t, actualErr)
+
+	_, actualErr = WithParallelBranches[int](Steps(addOne), Steps(toString)).
+		Validate(ArgTypes{reflect.TypeFor[branch]()})
+	assert.ErrorIs(t, actualErr, ErrIncompatibleInArgType)
+	assert.ErrorContains(t, actualErr, "step validation failed [Map:1]: incompatible input argument type [int!=string:1]")
+
+	_, actualErr = WithParallelBranches[int](Steps(addOne)).
+		Validate(ArgTypes{reflect.TypeFor[int]()})
+	assert.ErrorIs(t, actualErr, ErrIncompatibleInArgType)
+}
+
+func TestMerge_RestoresInputOrder(t *testing.T) {
+	actual := Transform[branch]([]branch{
+		{seq: 2, value: 2},
+		{seq: 3, skipped: true},
+		{seq: 1, value: 1},
+		{seq: 5, value: 5},
+		{seq: 4, value: 4},
+		{seq: 7, value: 7},
+	}, WithErrorHandler(expectsError(t, false))).
+		WithSteps(Merge()).
+		AsSlice()
+
+	assert.Equal(t, []any{1, 2, 4, 5, 7}, actual)
+}
+
 func expectsError(t *testing.T, expectsError bool) func(error) {
 	t.Helper()
 	return func(err error) {
@@ -625,6 +1759,78 @@ func ExampleMap() {
 	// Output: [h e l l o]
 }
 
+func ExampleMap1To2() {
+	res := Transform[string]([]string{"a=1", "b=2", "c=3"}).
+		WithSteps(
+			Map1To2(func(in string) (string, string, error) {
+				key, value, _ := strings.Cut(in, "=")
+				return key, value, nil
+			}),
+			Map2To2(func(key, value string) (string, int, error) {
+				num, err := strconv.Atoi(value)
+				return key, num, err
+			}),
+			Filter2(func(key string, value int) (bool, error) {
+				return value%2 == 1, nil
+			}),
+		).AsMap()
+
+	fmt.Println(res)
+	// Output: map[a:1 c:3]
+}
+
+func ExampleMap2To1() {
+	res := Transform[int]([]int{1, 2, 3}).
+		WithSteps(
+			Map1To2(func(in int) (int, int, error) {
+				return in, in * in, nil
+			}),
+			Map2To1(func(in, square int) (string, error) {
+				return fmt.Sprintf("%d^2=%d", in, square), nil
+			}),
+		).AsSlice()
+
+	fmt.Println(res)
+	// Output: [1^2=1 2^2=4 3^2=9]
+}
+
+func ExampleParallelMap() {
+	res := Transform[int]([]int{3, 2, 1}).
+		WithSteps(
+			ParallelMap(3, func(in int) (int, error) {
+				time.Sleep(time.Duration(in) * time.Millisecond)
+				return in * in, nil
+			}),
+		).AsSlice()
+
+	fmt.Println(res)
+	// Output: [9 4 1]
+}
+
+func ExampleFlatMap() {
+	res := Transform[string]([]string{"a b", "c"}).
+		WithSteps(
+			FlatMap(func(in string) ([]string, error) {
+				return strings.Fields(in), nil
+			}),
+		).AsSlice()
+
+	fmt.Println(res)
+	// Output: [a b c]
+}
+
+func ExampleFlatMapSeq() {
+	res := Transform[string]([]string{"a,b", "c"}).
+		WithSteps(
+			FlatMapSeq(func(in string) (iter.Seq[string], error) {
+				return slices.Values(strings.Split(in, ",")), nil
+			}),
+		).AsSlice()
+
+	fmt.Println(res)
+	// Output: [a b c]
+}
+
 func ExampleFilter() {
 	res := Transform[int]([]int{1, 2, 3, 4, 5}).
 		WithSteps(
@@ -681,6 +1887,16 @@ func ExampleSkipWhile() {
 	// Output: [4 5]
 }
 
+func ExampleBatch() {
+	res := Transform[int]([]int{1, 2, 3, 4, 5}).
+		WithSteps(
+			Batch[int](2, time.Second),
+		).AsSlice()
+
+	fmt.Println(res)
+	// Output: [[1 2] [3 4] [5]]
+}
+
 func ExampleDo() {
 	total := 0
 	res := Transform[int]([]int{1, 2, 3, 4, 5}).
@@ -751,7 +1967,234 @@ func ExampleWithBranches() {
 	// Output: []interface {}{"10", "-2", "30", "-4", "50", "-6", "70", "-8", "90", "-10"}
 }
 
+func ExampleWithParallelBranches() {
+	res := Transform[int]([]int{1, 2, 3, 4, 5, 6}).
+		WithSteps(
+			Split(func(in int) (uint8, error) {
+				return uint8(in % 2), nil
+			}),
+			WithParallelBranches[int](
+				Steps(
+					Map(func(in int) (string, error) {
+						time.Sleep(time.Millisecond)
+						return strconv.Itoa(-in), nil
+					}),
+				),
+				Steps(
+					Map(func(in int) (string, error) {
+						return strconv.Itoa(in * 10), nil
+					}),
+				),
+			),
+			Merge(),
+		).
+		AsSlice()
+
+	fmt.Printf("%#v", res)
+	// Output: []interface {}{"10", "-2", "30", "-4", "50", "-6"}
+}
+
 func ExampleMerge() {
 	fmt.Println("see WithBranches")
 	// Output: see WithBranches
 }
+
+type testCtxKey struct{}
+
+func TestCtxSteps_PassTransformerContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), testCtxKey{}, 10)
+	multiplier := func(ctx context.Context) int {
+		return ctx.Value(testCtxKey{}).(int)
+	}
+	minutesOf := func(ctx context.Context, in int) (time.Time, error) {
+		return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(in*multiplier(ctx)) * time.Minute), nil
+	}
+	windowLen := Map(func(in Window[int]) (int, error) {
+		return len(in.Items), nil
+	})
+	pairOf := Map1To2(func(in int) (int, int, error) {
+		return in, in, nil
+	})
+	sumOf := Map2To1(func(in0, in1 int) (int, error) {
+		return in0 + in1, nil
+	})
+
+	for _, sc := range []struct {
+		name     string
+		steps    []StepWrapper
+		position int // position of the tested step in the steps
+		expected []any
+	}{
+		{
+			name: "MapCtx",
+			steps: []StepWrapper{MapCtx(func(ctx context.Context, in int) (int, error) {
+				return in * multiplier(ctx), nil
+			})},
+			expected: []any{10, 20, 30},
+		}, {
+			name: "ParallelMapCtx",
+			steps: []StepWrapper{ParallelMapCtx(2, func(ctx context.Context, in int) (int, error) {
+				return in * multiplier(ctx), nil
+			})},
+			expected: []any{10, 20, 30},
+		}, {
+			name: "FlatMapCtx",
+			steps: []StepWrapper{FlatMapCtx(func(ctx context.Context, in int) ([]int, error) {
+				return []int{in, in * multiplier(ctx)}, nil
+			})},
+			expected: []any{1, 10, 2, 20, 3, 30},
+		}, {
+			name: "FlatMapSeqCtx",
+			steps: []StepWrapper{FlatMapSeqCtx(func(ctx context.Context, in int) (iter.Seq[int], error) {
+				return slices.Values([]int{in * multiplier(ctx)}), nil
+			})},
+			expected: []any{10, 20, 30},
+		}, {
+			name: "FilterCtx",
+			steps: []StepWrapper{FilterCtx(func(ctx context.Context, in int) (bool, error) {
+				return in*multiplier(ctx) > 10, nil
+			})},
+			expected: []any{2, 3},
+		}, {
+			name: "TakeWhileCtx",
+			steps: []StepWrapper{TakeWhileCtx(func(ctx context.Context, in int) (bool, error) {
+				return in*multiplier(ctx) < 30, nil
+			})},
+			expected: []any{1, 2},
+		}, {
+			name: "SkipWhileCtx",
+			steps: []StepWrapper{SkipWhileCtx(func(ctx context.Context, in int) (bool, error) {
+				return in*multiplier(ctx) < 20, nil
+			})},
+			expected: []any{2, 3},
+		}, {
+			name: "DoCtx",
+			steps: []StepWrapper{DoCtx(func(ctx context.Context, in int) error {
+				if multiplier(ctx) != 10 {
+					return errors.New("missing context value")
+				}
+				return nil
+			})},
+			expected: []any{1, 2, 3},
+		}, {
+			name: "SplitCtx",
+			steps: []StepWrapper{
+				SplitCtx(func(ctx context.Context, in int) (uint8, error) {
+					return uint8(in * multiplier(ctx) / 20), nil
+				}),
+				WithBranches[int](
+					Steps(Map(func(in int) (int, error) {
+						return -in, nil
+					})),
+					Steps(),
+				),
+				Merge(),
+			},
+			expected: []any{-1, 2, 3},
+		}, {
+			name: "Map1To2Ctx",
+			steps: []StepWrapper{
+				Map1To2Ctx(func(ctx context.Context, in int) (int, int, error) {
+					return in, in * multiplier(ctx), nil
+				}),
+				sumOf,
+			},
+			expected: []any{11, 22, 33},
+		}, {
+			name: "Map2To1Ctx",
+			steps: []StepWrapper{
+				pairOf,
+				Map2To1Ctx(func(ctx context.Context, in0, in1 int) (int, error) {
+					return in0 + in1*multiplier(ctx), nil
+				}),
+			},
+			position: 1,
+			expected: []any{11, 22, 33},
+		}, {
+			name: "Map2To2Ctx",
+			steps: []StepWrapper{
+				pairOf,
+				Map2To2Ctx(func(ctx context.Context, in0, in1 int) (int, int, error) {
+					return in0, in1 * multiplier(ctx), nil
+				}),
+				sumOf,
+			},
+			position: 1,
+			expected: []any{11, 22, 33},
+		}, {
+			name: "Filter2Ctx",
+			steps: []StepWrapper{
+				pairOf,
+				Filter2Ctx(func(ctx context.Context, in0, in1 int) (bool, error) {
+					return in0*multiplier(ctx) > 10, nil
+				}),
+				sumOf,
+			},
+			position: 1,
+			expected: []any{4, 6},
+		}, {
+			name:     "TumblingWindowCtx",
+			steps:    []StepWrapper{TumblingWindowCtx(25*time.Minute, minutesOf), windowLen},
+			expected: []any{2, 1},
+		}, {
+			name:     "SlidingWindowCtx",
+			steps:    []StepWrapper{SlidingWindowCtx(20*time.Minute, 10*time.Minute, minutesOf), windowLen},
+			expected: []any{1, 2, 2, 1},
+		}, {
+			name:     "SessionWindowCtx",
+			steps:    []StepWrapper{SessionWindowCtx(5*time.Minute, minutesOf), windowLen},
+			expected: []any{1, 1, 1},
+		}, {
+			name: "ThrottleByCtx",
+			steps: []StepWrapper{ThrottleByCtx(1000, 1, func(ctx context.Context, in int) (int, error) {
+				return in * multiplier(ctx), nil
+			})},
+			expected: []any{1, 2, 3},
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			assert.Equal(t, sc.name, sc.steps[sc.position].Name)
+			actual := Transform[int]([]int{1, 2, 3}, WithContext(ctx), WithErrorHandler(expectsError(t, false))).
+				WithSteps(sc.steps...).
+				AsSlice()
+
+			assert.Equal(t, sc.expected, actual)
+		})
+	}
+}
+
+func ExampleMapCtx() {
+	type tenantKey struct{}
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+
+	res := Transform[int]([]int{1, 2}, WithContext(ctx)).
+		WithSteps(
+			MapCtx(func(ctx context.Context, in int) (string, error) {
+				return fmt.Sprintf("%s-%d", ctx.Value(tenantKey{}), in), nil
+			}),
+		).
+		AsSlice()
+
+	fmt.Println(res)
+	// Output: [acme-1 acme-2]
+}
+
+func ExampleDoCtx() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var transformErr error
+	res := Transform[int]([]int{1, 2, 3}, WithContext(ctx), WithErrorHandler(func(err error) {
+		transformErr = err
+	})).
+		WithSteps(
+			DoCtx(func(ctx context.Context, in int) error {
+				if in == 2 {
+					cancel()
+				}
+				return ctx.Err()
+			}),
+		).
+		AsSlice()
+
+	fmt.Println(res, transformErr)
+	// Output: [1] context canceled
+}
@@ -163,6 +163,98 @@ func BenchmarkTransformerMultipleSteps(b *testing.B) {
 	assert.Equal(b, 28, res[0])
 }
 
+// BenchmarkTransformerMultipleStepsBuiltPerRun is a variant of BenchmarkTransformerMultipleSteps
+// where the steps are created and validated by each run (like when the chain is built inline)
+func BenchmarkTransformerMultipleStepsBuiltPerRun(b *testing.B) {
+	b.StopTimer()
+
+	var res []any
+	input := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}
+
+	b.ReportAllocs()
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		res = res[:0]
+		iter := Transform[string](input).
+			With(Steps(
+				Skip[string](3),
+				Map(func(i string) (int, error) {
+					return strconv.Atoi(i)
+				}),
+				Filter(func(i int) (bool, error) {
+					return i%2 == 0, nil
+				}),
+			).Aggregate(Sum[int]())).
+			AsRange()
+		for i := range iter {
+			res = append(res, i)
+		}
+	}
+	assert.Equal(b, 28, res[0])
+}
+
+func BenchmarkGeneratedMultipleSteps(b *testing.B) {
+	b.StopTimer()
+
+	var res []int
+	var err error
+	input := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}
+
+	b.ReportAllocs()
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		res, err = multipleStepsGenerated(input)
+	}
+	require.NoError(b, err)
+	assert.Equal(b, 28, res[0])
+}
+
+func BenchmarkTransformerFusedSteps(b *testing.B) {
+	for _, sc := range []struct {
+		name    string
+		options []func(*TransformerOptions)
+	}{
+		{name: "fused"},
+		{name: "not_fused", options: []func(*TransformerOptions){WithoutFusion()}},
+	} {
+		b.Run(sc.name, func(b *testing.B) {
+			b.StopTimer()
+
+			steps := Steps(
+				Map(func(i string) (int, error) {
+					return strconv.Atoi(i)
+				}),
+				Filter(func(i int) (bool, error) {
+					return i%2 == 0, nil
+				}),
+				Map(func(i int) (int, error) {
+					return i * 1000, nil
+				}),
+				Map(func(i int) (float64, error) {
+					return float64(i) / 3, nil
+				}),
+			)
+			require.NoError(b, steps.Validate())
+
+			var res []any
+			input := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}
+
+			b.ReportAllocs()
+			b.StartTimer()
+			for i := 0; i < b.N; i++ {
+				res = res[:0]
+				iter := Transform[string](input, sc.options...).
+					With(steps).
+					AsRange()
+				for i := range iter {
+					res = append(res, i)
+				}
+			}
+			assert.Len(b, res, 5)
+		})
+	}
+}
+
 const expectedJson = "[{\"id\":1,\"name\":\"John Doe\",\"age\":25,\"department\":\"Engineering\",\"salary\":60000,\"city\":\"New York\"},{\"id\":21,\"name\":\"Mark King\",\"age\":36,\"department\":\"Engineering\",\"salary\":78000,\"city\":\"New York\"}]"
 
 func BenchmarkNativeCsvToJsonSteps(b *testing.B) {
@@ -0,0 +1,122 @@
+package test
+
+//go:generate go run ../cmd/stepsgen -file pipelines_test.go
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/domahidizoltan/go-steps"
+	"github.com/stretchr/testify/assert"
+)
+
+//stepsgen:pipeline
+var multipleSteps = Steps(
+	Skip[string](3),
+	Map(func(i string) (int, error) {
+		return strconv.Atoi(i)
+	}),
+	Filter(func(i int) (bool, error) {
+		return i%2 == 0, nil
+	}),
+).Aggregate(Sum[int]())
+
+//stepsgen:pipeline
+func evenSquares(limit uint64) StepsBranch {
+	return Steps(
+		Take[int](limit),
+		Filter(func(i int) (bool, error) {
+			return i%2 == 0, nil
+		}),
+		Map(func(i int) (string, error) {
+			return strconv.Itoa(i * i), nil
+		}),
+	)
+}
+
+//stepsgen:pipeline
+func whileSteps() StepsBranch {
+	return Steps(
+		SkipWhile(func(s string) (bool, error) {
+			return s != "start", nil
+		}),
+		TakeWhile(func(s string) (bool, error) {
+			return s != "stop", nil
+		}),
+		Map[string, string](toUpper),
+		Do(func(s string) error {
+			if s == "FAIL" {
+				return errors.New("failed")
+			}
+			return nil
+		}),
+	).Aggregate(Fold("", func(acc, s string) (string, error) {
+		return acc + s, nil
+	}))
+}
+
+func TestGeneratedPipelines(t *testing.T) {
+	t.Run("aggregated", func(t *testing.T) {
+		input := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}
+		expected := Transform[string](input).With(multipleSteps).AsSlice()
+
+		actual, err := multipleStepsGenerated(input)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{28}, actual)
+		assert.Equal(t, expected, toAny(actual))
+	})
+
+	t.Run("nothing_aggregated", func(t *testing.T) {
+		actual, err := multipleStepsGenerated([]string{"1"})
+
+		assert.NoError(t, err)
+		assert.Empty(t, actual)
+	})
+
+	t.Run("with_params", func(t *testing.T) {
+		input := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+		expected := Transform[int](input).With(evenSquares(7)).AsSlice()
+
+		actual, err := evenSquaresGenerated(7, input)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"4", "16", "36"}, actual)
+		assert.Equal(t, expected, toAny(actual))
+	})
+
+	t.Run("stateful_steps", func(t *testing.T) {
+		input := []string{"a", "start", "b", "c", "stop", "d"}
+		expected := Transform[string](input).With(whileSteps()).AsSlice()
+
+		actual, err := whileStepsGenerated(input)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"STARTBC"}, actual)
+		assert.Equal(t, expected, toAny(actual))
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		actual, err := multipleStepsGenerated([]string{"1", "2", "3", "4", "x"})
+		assert.ErrorIs(t, err, strconv.ErrSyntax)
+		assert.Nil(t, actual)
+
+		aggregated, err := whileStepsGenerated([]string{"start", "fail"})
+		assert.EqualError(t, err, "failed")
+		assert.Nil(t, aggregated)
+	})
+}
+
+func toUpper(s string) (string, error) {
+	return strings.ToUpper(s), nil
+}
+
+func toAny[T any](items []T) []any {
+	res := make([]any, 0, len(items))
+	for _, i := range items {
+		res = append(res, i)
+	}
+	return res
+}
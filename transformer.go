@@ -1,45 +1,204 @@
 package steps
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
 	"reflect"
+	"sync"
+	"time"
 )
 
 type (
 	inputType[T any] interface {
-		chan T | []T
+		chan T | []T | iter.Seq[T] | iter.Seq2[any, T] | pipedInput
 	}
 
 	input[T any, IT inputType[T]] struct {
-		data    IT
-		options TransformerOptions
+		data      IT
+		runData   func(TransformerOptions) IT // creates the data with the options of each run (like the error handler of AsSliceE)
+		options   TransformerOptions
+		pipe      *pipeSource
+		streaming bool         // the input is created by [TransformFn], so it's producer is stopped when the run is finished
+		keyType   reflect.Type // the type of the input keys used by the indexed outputs
+	}
+
+	// pipedInput is the input of a transformer created by [Pipe].
+	// It yields the outputs (StepOutput) of the piped transformer with the keys of their inputs.
+	pipedInput func(yield func(key, out any) bool)
+
+	// pipeSource describes the transformer piped into another transformer
+	pipeSource struct {
+		name     string
+		outTypes ArgTypes
+		keyType  reflect.Type
+		error    error
 	}
 
 	transformer struct {
 		options             TransformerOptions
 		error               error
+		piped               bool
+		done                bool // a step passed it's last output (see [StepOutput.Done]), so the input is not processed further
+		streaming           bool
 		aggregator          ReducerFn
-		lastAggregatedValue *StepOutput
+		aggregatorOptions   AggregatorOptions
+		aggregatorReset     func()
+		aggregatedItems     uint64
+		lastEmission        time.Time
+		lastAggregatedValue StepOutput
+		aggregatorName      string
+		outTypes            ArgTypes
+		keyType             reflect.Type
 		steps               []StepFn
+		fusions             []stepFusion
+		stepWrappers        []StepWrapper
 		stateResets         []func()
+		ready               []<-chan struct{} // reused by readyChans
+		running             runningStep       // the step running on the processing goroutine
+	}
+
+	// runningStep is the step called by the transformer with it's input, so it's panic could be reported with them
+	runningStep struct {
+		pos     int
+		args    Args
+		argsLen uint8
+		isSet   bool
 	}
 
 	stepsTransformer[T any, IT inputType[T]] struct {
-		input IT
+		input    IT
+		runInput func(TransformerOptions) IT
 		transformer
 	}
 )
 
 // TransformFn is an alternative for [Transform] where the input is a function.
 // This could be used to implement new input sources like files or database connections.
+// The input function is called once, when the output of the transformer is consumed first, with the options of that run
+// (so the errors of the input are also returned by the error-returning outputs like AsSliceE).
+// The context passed to the input function is canceled (with [ErrInputStopped] cause) when the transformer finished processing,
+// so the input should stop producing new items. The remaining items of a channel input are drained.
 func TransformFn[T any, IT inputType[T]](in func(TransformerOptions) IT, options ...func(*TransformerOptions)) input[T, IT] {
-	input := in(buildOpts(options...))
-	return Transform[T](input, options...)
+	var (
+		once sync.Once
+		data IT
+	)
+	i := Transform[T](data, options...)
+	i.runData = func(opts TransformerOptions) IT {
+		once.Do(func() {
+			data = in(opts)
+		})
+		return data
+	}
+	i.streaming = true
+	return i
+}
+
+// stop creates the streaming input of [TransformFn] with a canceled context, so it's producer stops right away
+func (i input[T, IT]) stop() {
+	ctx, cancel := context.WithCancelCause(i.options.Ctx)
+	cancel(ErrInputStopped)
+	opts := i.options
+	opts.Ctx = ctx
+	drainInput[T](i.runData(opts))
+}
+
+// TransformSource is an alternative for [Transform] where the input is an [InputSource] (like [CsvSource] or [JsonSource]).
+// The source is opened when the output of the transformer is consumed, and it is closed when the source is exhausted,
+// the consumer stopped early or the processing failed.
+// The errors of the source are passed to the error handler with the name and the offset of the source, and the processing stops.
+// The bad items are handled by the decode error policy (by default the first bad item stops the processing).
+func TransformSource[T any](src InputSource[T], options ...func(*TransformerOptions)) input[T, iter.Seq[T]] {
+	i := Transform[T, iter.Seq[T]](nil, options...)
+	i.runData = func(opts TransformerOptions) iter.Seq[T] {
+		return sourceSeq(src, opts)
+	}
+	return i
+}
+
+// sourceSeq reads the source using the options of the current run (it's context and error handler)
+func sourceSeq[T any](src InputSource[T], opts TransformerOptions) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if err := src.Open(opts.Ctx); err != nil {
+			src.Close()
+			opts.ErrorHandler(sourceError(opts, src, err))
+			return
+		}
+		defer func() {
+			if err := src.Close(); err != nil {
+				opts.ErrorHandler(sourceError(opts, src, err))
+			}
+		}()
+
+		failures := decodeFailures{policy: opts.DecodeErrorPolicy}
+		for {
+			if err := opts.Ctx.Err(); err != nil {
+				opts.ErrorHandler(sourceError(opts, src, err))
+				return
+			}
+			data, err := src.Next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				if err := failures.handle(err, false, nil); err != nil {
+					opts.ErrorHandler(sourceError(opts, src, err))
+					return
+				}
+				continue
+			}
+			if !yield(data) {
+				return
+			}
+		}
+	}
+}
+
+// sourceError adds the name and the offset of the source to the error (and the name of the transformer when it's defined).
+// The [DecodeError] is already holding the position of the bad item, so it's offset is not added.
+func sourceError[T any](opts TransformerOptions, src InputSource[T], err error) error {
+	meta := src.Meta()
+	var decodeErr *DecodeError
+	switch {
+	case errors.As(err, &decodeErr):
+	case len(meta.Name) != 0:
+		err = fmt.Errorf("%w [%s:%d]", err, meta.Name, meta.Offset)
+	default:
+		err = fmt.Errorf("%w [%d]", err, meta.Offset)
+	}
+	if len(opts.Name) != 0 {
+		err = fmt.Errorf("[%s] %w", opts.Name, err)
+	}
+	return err
 }
 
 // Transform creates a builder for the transforation chain.
-// It takes the input data (slice or chan) and the transformer options.
+// It takes the input data (slice, chan, iter.Seq or iter.Seq2 with any keys) and the transformer options.
+// The output of a transformer (like AsRange) could be the input of another transformer.
 func Transform[T any, IT inputType[T]](in IT, options ...func(*TransformerOptions)) input[T, IT] {
-	return input[T, IT]{in, buildOpts(options...)}
+	keyType := reflect.TypeFor[int]()
+	if _, ok := any(in).(iter.Seq2[any, T]); ok {
+		keyType = reflect.TypeFor[any]()
+	}
+	return input[T, IT]{data: in, options: buildOpts(options...), keyType: keyType}
+}
+
+// TransformSeq2 is an alternative for [Transform] where the input is a key-value iterator (like maps.All).
+// The values are processed by the steps, and the keys are passed to the indexed outputs (like AsIndexedRange).
+func TransformSeq2[K, T any](in iter.Seq2[K, T], options ...func(*TransformerOptions)) input[T, iter.Seq2[any, T]] {
+	seq := func(yield func(any, T) bool) {
+		for k, v := range in {
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+	i := Transform[T, iter.Seq2[any, T]](seq, options...)
+	i.keyType = reflect.TypeFor[K]()
+	return i
 }
 
 // Steps creates the steps of a transformation chain.
@@ -56,18 +215,43 @@ func (i input[T, IT]) WithSteps(steps ...StepWrapper) stepsTransformer[T, IT] {
 
 // With adds the steps to the existing transformation chain.
 func (i input[T, IT]) With(steps StepsBranch) stepsTransformer[T, IT] {
+	inTypes, keyType := ArgTypes{reflect.TypeFor[T]()}, i.keyType
+	if i.pipe != nil {
+		inTypes, keyType = i.pipe.outTypes, i.pipe.keyType
+	}
+
 	t := stepsTransformer[T, IT]{
 		transformer: transformer{
 			options: i.options,
-			error:   steps.Validate(),
+			error:   i.validate(&steps, inTypes),
+			piped:   i.pipe != nil,
 		},
 	}
 	if t.error != nil {
+		if i.streaming {
+			// the input is never processed, so it's producer is stopped
+			i.stop()
+		}
 		return t
 	}
 
 	t.input = i.data
+	t.runInput = i.runData
+	t.streaming = i.streaming
+
 	t.steps = steps.Steps
+	if !i.options.NoFusion {
+		t.fusions = steps.fusions.get(steps.StepWrappers)
+	}
+	t.keyType = keyType
+	t.outTypes = steps.outTypes
+	if t.outTypes[0] == nil || t.outTypes[0] == reflect.TypeFor[SkipFirstArgValidation]() {
+		t.outTypes = inTypes
+		if steps.AggregatorWrapper != nil {
+			t.outTypes = ArgTypes{}
+		}
+	}
+	t.stepWrappers = steps.StepWrappers
 	for _, s := range steps.StepWrappers {
 		if s.Reset != nil {
 			t.stateResets = append(t.stateResets, s.Reset)
@@ -76,6 +260,9 @@ func (i input[T, IT]) With(steps StepsBranch) stepsTransformer[T, IT] {
 
 	if steps.AggregatorWrapper != nil {
 		t.aggregator = steps.AggregatorWrapper.ReducerFn
+		t.aggregatorName = steps.AggregatorWrapper.Name
+		t.aggregatorOptions = steps.AggregatorOptions
+		t.aggregatorReset = steps.AggregatorWrapper.Reset
 		if steps.AggregatorWrapper.Reset != nil {
 			t.stateResets = append(t.stateResets, steps.AggregatorWrapper.Reset)
 		}
@@ -84,34 +271,133 @@ func (i input[T, IT]) With(steps StepsBranch) stepsTransformer[T, IT] {
 	return t
 }
 
-// Aggregate adds a reducer to the transformer
-func Aggregate(fn ReducerWrapper) StepsBranch {
+// validate validates the steps. The steps of a piped transformer are validated against the output types of the piped transformer.
+func (i input[T, IT]) validate(steps *StepsBranch, inTypes ArgTypes) error {
+	if i.pipe == nil {
+		return steps.Validate()
+	}
+	if i.pipe.error != nil {
+		return i.pipe.error
+	}
+
+	if inTypes[0] == nil {
+		inTypes = ArgTypes{reflect.TypeFor[SkipFirstArgValidation]()}
+	}
+	err := steps.validate(inTypes)
+	if err != nil && len(i.pipe.name) != 0 {
+		err = fmt.Errorf("%w (piped from %s)", err, i.pipe.name)
+	}
+	return err
+}
+
+// Pipe passes the output of the transformer to a new transformer, which is created with the given options.
+// The transformers are connected lazily, without collecting the outputs of the piped transformer
+// (only the next output is read ahead to detect the last item).
+// The steps of the new transformer are validated against the output types of the piped transformer.
+// The errors of the piped transformer are passed to the error handler of the new transformer.
+func Pipe[T any, IT inputType[T]](t stepsTransformer[T, IT], options ...func(*TransformerOptions)) input[any, pipedInput] {
+	opts := buildOpts(options...)
+	pipe := pipeSource{
+		name:     t.options.Name,
+		outTypes: t.outTypes,
+		keyType:  t.keyType,
+	}
+	if t.error != nil {
+		pipe.error = errWithTrName(t, t.error)
+	}
+
+	return input[any, pipedInput]{
+		runData: func(runOpts TransformerOptions) pipedInput {
+			// the errors are passed to the error handler of the current run (like the error collector of AsSliceE)
+			return t.pipe(func(err error) {
+				if len(runOpts.Name) != 0 {
+					err = fmt.Errorf("[%s] %w", runOpts.Name, err)
+				}
+				runOpts.ErrorHandler(err)
+			})
+		},
+		options: opts,
+		pipe:    &pipe,
+	}
+}
+
+// Then is a shortcut to Pipe(t, options...).With(steps)
+func (t stepsTransformer[T, IT]) Then(steps StepsBranch, options ...func(*TransformerOptions)) stepsTransformer[any, pipedInput] {
+	return Pipe(t, options...).With(steps)
+}
+
+// pipe returns the outputs of the transformer as the input of another transformer
+func (t stepsTransformer[T, IT]) pipe(errorHandler func(error)) pipedInput {
+	return func(yield func(any, any) bool) {
+		t.options.ErrorHandler = errorHandler
+		defer t.finishRun(t.startRun())
+		if t.error != nil {
+			return
+		}
+
+		t.forEachInput(func(key any, val T, isLastItem bool) bool {
+			_, terminated, err := t.processItem(val, isLastItem, func(out StepOutput) bool {
+				return yield(key, out)
+			})
+			if err != nil {
+				handleErrWithTrName(t, err, errorHandler)
+			}
+			return !terminated && err == nil
+		}, func(key any, isLastItem bool) bool {
+			terminated, err := t.flushInput(isLastItem, func(out StepOutput) bool {
+				return yield(key, out)
+			})
+			if err != nil {
+				handleErrWithTrName(t, err, errorHandler)
+			}
+			return !terminated && err == nil
+		})
+	}
+}
+
+// Aggregate adds a reducer to the transformer.
+// The options are defining when the aggregated value is emitted (see [AggregatorOptions]).
+func Aggregate(fn ReducerWrapper, options ...func(*AggregatorOptions)) StepsBranch {
 	return StepsBranch{
 		AggregatorWrapper: &fn,
+		AggregatorOptions: buildAggregatorOpts(options...),
 	}
 }
 
-// Aggregate ads a reducer to the transformer with steps previously defined
-func (s StepsBranch) Aggregate(fn ReducerWrapper) StepsBranch {
+// Aggregate ads a reducer to the transformer with steps previously defined.
+// The options are defining when the aggregated value is emitted (see [AggregatorOptions]).
+func (s StepsBranch) Aggregate(fn ReducerWrapper, options ...func(*AggregatorOptions)) StepsBranch {
 	return StepsBranch{
 		StepWrappers:      s.StepWrappers,
 		AggregatorWrapper: &fn,
+		AggregatorOptions: buildAggregatorOpts(options...),
 	}
 }
 
 // Validate is runs the validation for the steps in the transformation chain.
 // One of the main purpose of the validator is to reduce the possible runtime errors caused by reflection usage.
 // The validator tries to check that the output of the steps are matching the inputs of the next steps.
+// The consecutive stateless steps (like Map and Filter) are fused into a single step once per validated chain, when it is added to a transformer first (see [WithoutFusion]).
 // It could be triggered explicitly to validate the chain before running it,
 // but it will also run automatically (if not ran before) when the chain is processing it's first item.
+// The validated steps could be reused by multiple transformers without validating them again.
 func (s *StepsBranch) Validate() error {
+	return s.validate(ArgTypes{reflect.TypeFor[SkipFirstArgValidation]()})
+}
+
+// validate runs the validation for the steps, where the first step is receiving the given input types
+func (s *StepsBranch) validate(inTypes ArgTypes) error {
 	if s.Error != nil {
 		s.StepWrappers = nil
 		return s.Error
 	}
+	if s.fusions != nil && s.validatedIn == inTypes {
+		return nil
+	}
 
 	var lastOutTypes ArgTypes
-	s.Steps, lastOutTypes, s.Error = getValidatedSteps[SkipFirstArgValidation](s.StepWrappers)
+	s.Steps, lastOutTypes, s.Error = validateSteps(s.StepWrappers, inTypes)
+	s.outTypes = lastOutTypes
 
 	aggWr := s.AggregatorWrapper
 	if aggWr != nil {
@@ -127,14 +413,39 @@ func (s *StepsBranch) Validate() error {
 		}
 
 		if s.Steps == nil {
-			lastOutTypes = ArgTypes{reflect.TypeOf(SkipFirstArgValidation{})}
+			lastOutTypes = inTypes
+		}
+		var aggOutTypes ArgTypes
+		aggOutTypes, s.Error = aggWr.Validate(lastOutTypes)
+		s.outTypes = aggregatedTypes(aggOutTypes)
+
+		if triggerType := s.AggregatorOptions.triggerType; s.Error == nil && triggerType != nil {
+			if err := argTypesValidation(ArgTypes{lastOutTypes[0]}, ArgTypes{triggerType}); err != nil {
+				s.Error = fmt.Errorf("%w [EmitWhen]: %w", ErrStepValidationFailed, err)
+			}
 		}
-		_, s.Error = aggWr.Validate(lastOutTypes)
 	}
 
+	if s.Error == nil {
+		s.validatedIn, s.fusions = inTypes, &stepFusions{}
+	}
 	return s.Error
 }
 
+// aggregatedTypes returns the type of the value emitted by an aggregator from the validated output types of the aggregator.
+// The aggregators with multiple output types (like GroupBy) are describing their groups,
+// so their aggregated value is a map of the group slices (unknown when the group key is not comparable).
+func aggregatedTypes(aggOutTypes ArgTypes) ArgTypes {
+	switch {
+	case aggOutTypes[1] == nil:
+		return aggOutTypes
+	case aggOutTypes[0].Comparable():
+		return ArgTypes{reflect.MapOf(aggOutTypes[0], reflect.SliceOf(aggOutTypes[1]))}
+	default:
+		return ArgTypes{}
+	}
+}
+
 func (t transformer) resetStates() {
 	for _, reset := range t.stateResets {
 		reset()
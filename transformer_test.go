@@ -1,9 +1,15 @@
 package steps
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"reflect"
+	"slices"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -292,3 +298,396 @@ func funcPtrAreEqual(expected, actual any) bool {
 
 	return expectedFn.Pointer() == actualFn.Pointer()
 }
+
+func TestAggregate_EmissionPolicy(t *testing.T) {
+	for _, sc := range []struct {
+		name     string
+		delay    time.Duration
+		options  []func(*AggregatorOptions)
+		expected []any
+	}{
+		{
+			name:     "emit_on_last_item_by_default",
+			expected: []any{21},
+		}, {
+			name:     "emit_every_n_items",
+			options:  []func(*AggregatorOptions){EmitEvery(2)},
+			expected: []any{3, 10, 21},
+		}, {
+			name:     "emit_every_n_items_without_remaining",
+			options:  []func(*AggregatorOptions){EmitEvery(3)},
+			expected: []any{6, 21},
+		}, {
+			name:     "emit_every_n_items_and_reset",
+			options:  []func(*AggregatorOptions){EmitEvery(2), ResetOnEmit()},
+			expected: []any{3, 7, 11},
+		}, {
+			name: "emit_when_triggered",
+			options: []func(*AggregatorOptions){EmitWhen(func(in int) bool {
+				return in%3 == 0
+			})},
+			expected: []any{6, 21},
+		}, {
+			name: "emit_when_triggered_and_reset",
+			options: []func(*AggregatorOptions){EmitWhen(func(in int) bool {
+				return in == 4
+			}), ResetOnEmit()},
+			expected: []any{10, 11},
+		}, {
+			name:     "emit_every_duration",
+			delay:    2 * time.Millisecond,
+			options:  []func(*AggregatorOptions){EmitEveryDuration(time.Millisecond), ResetOnEmit()},
+			expected: []any{3, 3, 4, 5, 6},
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			actual := Transform[int]([]int{1, 2, 3, 4, 5, 6}, WithErrorHandler(expectsError(t, false))).
+				With(Steps(
+					Do(func(int) error {
+						time.Sleep(sc.delay)
+						return nil
+					}),
+				).Aggregate(Sum[int](), sc.options...)).
+				AsSlice()
+
+			assert.Equal(t, sc.expected, actual)
+		})
+	}
+}
+
+func TestAggregate_ResetOnEmitAvg(t *testing.T) {
+	actual := Transform[float64]([]float64{1, 3, 10, 20}, WithErrorHandler(expectsError(t, false))).
+		With(Steps(Map(func(in float64) (float64, error) {
+			return in, nil
+		})).Aggregate(Avg(), EmitEvery(2), ResetOnEmit())).
+		AsSlice()
+
+	assert.Equal(t, []any{2.0, 15.0}, actual)
+}
+
+func TestAggregate_EmitEveryDurationIdleInput(t *testing.T) {
+	inputCh := make(chan int)
+	values := make(chan any)
+	go func() {
+		defer close(values)
+		for value := range Transform[int](inputCh, WithErrorHandler(expectsError(t, false))).
+			With(Steps(Map(func(in int) (int, error) {
+				return in, nil
+			})).Aggregate(Sum[int](), EmitEveryDuration(10*time.Millisecond), ResetOnEmit())).
+			AsRange() {
+			values <- value
+		}
+	}()
+
+	inputCh <- 1
+	inputCh <- 2
+	select {
+	case actual := <-values:
+		assert.Equal(t, 3, actual)
+	case <-time.After(time.Second):
+		assert.Fail(t, "aggregated value is not emitted while the input is idle")
+	}
+
+	inputCh <- 3
+	close(inputCh)
+	var actual []any
+	for value := range values {
+		actual = append(actual, value)
+	}
+	assert.Equal(t, []any{3}, actual)
+}
+
+func TestAggregate_EmitWhenWithIncompatibleTrigger(t *testing.T) {
+	steps := Steps(Map(func(in int) (int, error) {
+		return in, nil
+	})).Aggregate(Sum[int](), EmitWhen(func(string) bool {
+		return true
+	}))
+	err := steps.Validate()
+
+	assert.ErrorIs(t, err, ErrStepValidationFailed)
+	assert.ErrorIs(t, err, ErrIncompatibleInArgType)
+	assert.ErrorContains(t, err, "[EmitWhen]")
+}
+
+func TestAggregate_EmitWhenPanicIsRecovered(t *testing.T) {
+	var actualErr error
+	actual := Transform[int]([]int{1, 2, 3}, WithErrorHandler(func(err error) {
+		actualErr = err
+	})).
+		With(Steps().Aggregate(Sum[int](), EmitWhen(func(in int) bool {
+			if in == 2 {
+				panic("trigger failed")
+			}
+			return false
+		}))).
+		AsSlice()
+
+	assert.Empty(t, actual)
+	var panicErr *StepPanicError
+	if assert.ErrorAs(t, actualErr, &panicErr) {
+		assert.ErrorIs(t, actualErr, ErrStepPanicked)
+		assert.Equal(t, "Sum", panicErr.Step)
+		assert.Equal(t, "trigger failed", panicErr.Value)
+	}
+}
+
+func TestAggregate_EmitsSnapshotsOfStreamingInput(t *testing.T) {
+	input := make(chan int)
+	actual := make(chan any)
+	go func() {
+		for res := range Transform[int](input, WithErrorHandler(expectsError(t, false))).
+			With(Aggregate(
+				GroupBy(func(in int) (int, int, error) {
+					return in % 2, in, nil
+				}),
+				EmitEvery(2),
+			)).
+			AsRange() {
+			actual <- res
+		}
+		close(actual)
+	}()
+
+	// channel inputs are processed when the next item arrives
+	input <- 1
+	input <- 2
+	input <- 3
+	assert.Equal(t, map[int][]int{0: {2}, 1: {1}}, <-actual)
+
+	input <- 4
+	input <- 5
+	assert.Equal(t, map[int][]int{0: {2, 4}, 1: {1, 3}}, <-actual)
+
+	close(input)
+	assert.Equal(t, map[int][]int{0: {2, 4}, 1: {1, 3, 5}}, <-actual)
+	_, ok := <-actual
+	assert.False(t, ok)
+}
+
+func ExampleTransformSeq2() {
+	res := TransformSeq2(slices.All([]string{"a", "bb", "ccc"})).
+		WithSteps(
+			Map(func(in string) (int, error) {
+				return len(in), nil
+			}),
+		).
+		AsMap()
+
+	fmt.Println(res)
+	// Output: map[0:1 1:2 2:3]
+}
+
+func TestTransformSource(t *testing.T) {
+	csvInput := "name,code\nJohn,1\nJane,2\nxxxx\nDoe,3\n"
+	toName := Map(func(in testPerson) (string, error) {
+		return in.Name, nil
+	})
+
+	for _, sc := range []struct {
+		name          string
+		input         io.Reader
+		take          int
+		expected      []string
+		expectedError string
+	}{
+		{
+			name:     "consumer_stopped_early",
+			input:    strings.NewReader(csvInput),
+			take:     1,
+			expected: []string{"John"},
+		}, {
+			name:          "decode_error",
+			input:         strings.NewReader(csvInput),
+			take:          10,
+			expected:      []string{"John", "Jane"},
+			expectedError: "[persons] decode failed [4:1]: record on line 4: wrong number of fields",
+		}, {
+			name:          "open_error",
+			input:         failReader{errors.New("reader error")},
+			take:          10,
+			expectedError: "[persons] reader error [0]",
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			var errs []string
+			input := &closeRecorder{Reader: sc.input}
+			src := CsvSource[testPerson](input, false)
+
+			var actual []string
+			for res := range TransformSource(src, WithName("persons"), WithErrorHandler(func(err error) {
+				errs = append(errs, err.Error())
+			})).With(Steps(toName)).AsRange() {
+				actual = append(actual, res.(string))
+				if len(actual) == sc.take {
+					break
+				}
+			}
+
+			assert.Equal(t, sc.expected, actual)
+			if len(sc.expectedError) != 0 {
+				assert.Equal(t, []string{sc.expectedError}, errs)
+			} else {
+				assert.Empty(t, errs)
+			}
+			assert.True(t, input.closed.Load())
+		})
+	}
+}
+
+func ExampleTransformSource() {
+	type person struct {
+		ID   int    `csv:"id"`
+		Name string `csv:"name"`
+	}
+	reader := strings.NewReader("id,name\n1,John Doe\n2,Jane Doe")
+
+	res := TransformSource(CsvSource[person](reader, false)).
+		WithSteps(
+			Map(func(in person) (string, error) {
+				return in.Name, nil
+			}),
+		).
+		AsSlice()
+
+	fmt.Println(res)
+	// Output: [John Doe Jane Doe]
+}
+
+func TestPipe(t *testing.T) {
+	toLen := Map(func(in string) (int, error) {
+		return len(in), nil
+	})
+	failOn := func(value int) StepWrapper {
+		return Map(func(in int) (int, error) {
+			if in == value {
+				return 0, errors.New("step error")
+			}
+			return in, nil
+		})
+	}
+
+	t.Run("processed_lazily", func(t *testing.T) {
+		var processed []string
+		logStep := func(name string) StepWrapper {
+			return Do(func(in int) error {
+				processed = append(processed, fmt.Sprintf("%s:%d", name, in))
+				return nil
+			})
+		}
+
+		res := Transform[int]([]int{1, 2, 3}).
+			WithSteps(logStep("first")).
+			Then(Steps(logStep("second"))).
+			AsSlice()
+
+		assert.Equal(t, []any{1, 2, 3}, res)
+		// the next item of the piped transformer is processed before the current item, to know which one is the last
+		assert.Equal(t, []string{"first:1", "first:2", "second:1", "first:3", "second:2", "second:3"}, processed)
+	})
+
+	t.Run("multiple_args_passed", func(t *testing.T) {
+		first := Transform[string]([]string{"a", "bb", "ccc"}).
+			WithSteps(
+				Map1To2(func(in string) (string, int, error) {
+					return in, len(in), nil
+				}),
+			)
+
+		res := Pipe(first).
+			With(Steps(
+				Map2To1(func(in string, length int) (string, error) {
+					return fmt.Sprintf("%s=%d", in, length), nil
+				}),
+			).Aggregate(Fold("", func(acc, in string) (string, error) {
+				return acc + in + ";", nil
+			}))).
+			AsSlice()
+
+		assert.Equal(t, []any{"a=1;bb=2;ccc=3;"}, res)
+	})
+
+	t.Run("typed_output", func(t *testing.T) {
+		res, err := AsTypedSlice[int](Transform[string]([]string{"a", "bb"}).
+			WithSteps(toLen).
+			Then(Steps()))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, res)
+	})
+
+	t.Run("early_break", func(t *testing.T) {
+		var processed []int
+		second := Transform[int]([]int{1, 2, 3, 4, 5}).
+			WithSteps(Do(func(in int) error {
+				processed = append(processed, in)
+				return nil
+			})).
+			Then(Steps(filterEven))
+
+		for range second.AsRange() {
+			break
+		}
+
+		assert.Equal(t, []int{1, 2, 3}, processed)
+	})
+
+	t.Run("validated_across_transformers", func(t *testing.T) {
+		var actualErr error
+		res := Transform[string]([]string{"a"}, WithName("first")).
+			WithSteps(toLen).
+			Then(Steps(toLen), WithName("second"), WithErrorHandler(func(err error) {
+				actualErr = err
+			})).
+			AsSlice()
+
+		assert.Empty(t, res)
+		assert.ErrorIs(t, actualErr, ErrIncompatibleInArgType)
+		assert.EqualError(t, actualErr, "[second] step validation failed [Map:1]: incompatible input argument type [int!=string:1] (piped from first)")
+	})
+
+	t.Run("piped_transformer_invalid", func(t *testing.T) {
+		var actualErr error
+		res := Transform[int]([]int{1}, WithName("first")).
+			WithSteps(Take[int](1), toLen).
+			Then(Steps(), WithName("second"), WithErrorHandler(func(err error) {
+				actualErr = err
+			})).
+			AsSlice()
+
+		assert.Empty(t, res)
+		assert.ErrorIs(t, actualErr, ErrStepValidationFailed)
+		assert.ErrorContains(t, actualErr, "[second] [first] step validation failed [Map:2]")
+	})
+
+	t.Run("errors_passed_to_second_error_handler", func(t *testing.T) {
+		var errs []string
+		res := Transform[int]([]int{1, 2, 3, 4}, WithName("first"), WithErrorPolicy(Continue)).
+			WithSteps(failOn(1)).
+			Then(Steps(failOn(3)), WithName("second"), WithErrorHandler(func(err error) {
+				errs = append(errs, err.Error())
+			})).
+			AsSlice()
+
+		assert.Equal(t, []any{2}, res)
+		assert.Equal(t, []string{"[second] [first] step error", "[second] step error"}, errs)
+	})
+}
+
+func ExamplePipe() {
+	words := Transform[string]([]string{"go", "steps", "pipe"}).
+		WithSteps(
+			Map(func(in string) (int, error) {
+				return len(in), nil
+			}),
+		)
+
+	res := Pipe(words).
+		With(Steps(Filter(func(in int) (bool, error) {
+			return in > 2, nil
+		})).Aggregate(Sum[int]())).
+		AsSlice()
+
+	fmt.Println(res)
+	// Output: [9]
+}
@@ -0,0 +1,215 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// Window holds the inputs of a time window
+type Window[T any] struct {
+	Start time.Time // start of the window (inclusive)
+	End   time.Time // end of the window (exclusive)
+	Items []T       // the inputs in the window
+}
+
+// TumblingWindow groups the inputs into consecutive, non-overlapping time windows of the given size.
+// The time of the inputs is returned by timestampFn, and a window is emitted once an input arrives after it's end,
+// or when the input is exhausted. Inputs arriving after their window was emitted are dropped.
+func TumblingWindow[IN0 any](size time.Duration, timestampFn func(in IN0) (time.Time, error)) StepWrapper {
+	window := SlidingWindow(size, size, timestampFn)
+	window.Name = "TumblingWindow"
+	return window
+}
+
+// TumblingWindowCtx is a variant of [TumblingWindow] passing the context of the transformer to the timestamp function
+func TumblingWindowCtx[IN0 any](size time.Duration, timestampFn func(ctx context.Context, in IN0) (time.Time, error)) StepWrapper {
+	window := SlidingWindowCtx(size, size, timestampFn)
+	window.Name = "TumblingWindowCtx"
+	return window
+}
+
+// SlidingWindow groups the inputs into overlapping time windows of the given size, starting a new window at every slide.
+// The time of the inputs is returned by timestampFn, and a window is emitted once an input arrives after it's end,
+// or when the input is exhausted. Inputs arriving after their windows were emitted are dropped.
+// The slide is the same as the size when it's not positive, and the validation fails with [ErrInvalidStepConfig] when the size is not positive.
+func SlidingWindow[IN0 any](size, slide time.Duration, timestampFn func(in IN0) (time.Time, error)) StepWrapper {
+	window := SlidingWindowCtx(size, slide, func(_ context.Context, in IN0) (time.Time, error) {
+		return timestampFn(in)
+	})
+	window.Name = "SlidingWindow"
+	return window
+}
+
+// SlidingWindowCtx is a variant of [SlidingWindow] passing the context of the transformer to the timestamp function
+func SlidingWindowCtx[IN0 any](size, slide time.Duration, timestampFn func(ctx context.Context, in IN0) (time.Time, error)) StepWrapper {
+	if slide <= 0 {
+		slide = size
+	}
+	var watermark time.Time
+	windows := map[int64]*Window[IN0]{}
+	emit := func(closedOnly bool) StepOutput {
+		out := StepOutput{
+			MultiArgs: []Args{},
+			ArgsLen:   1,
+		}
+		for _, start := range slices.Sorted(maps.Keys(windows)) {
+			w := windows[start]
+			if closedOnly && w.End.After(watermark) {
+				break
+			}
+			out.MultiArgs = append(out.MultiArgs, Args{*w})
+			delete(windows, start)
+		}
+		return out
+	}
+
+	return StepWrapper{
+		Name: "SlidingWindowCtx",
+		StepFn: func(in StepInput) StepOutput {
+			ts, err := timestampFn(ctxOf(in), in.Args[0].(IN0))
+			if err != nil {
+				return StepOutput{Error: err}
+			}
+			if ts.After(watermark) {
+				watermark = ts
+			}
+
+			for start := ts.Truncate(slide); start.Add(size).After(ts); start = start.Add(-slide) {
+				end := start.Add(size)
+				if !end.After(watermark) {
+					break
+				}
+				w, ok := windows[start.UnixNano()]
+				if !ok {
+					w = &Window[IN0]{Start: start, End: end}
+					windows[start.UnixNano()] = w
+				}
+				w.Items = append(w.Items, in.Args[0].(IN0))
+			}
+
+			return emit(true)
+		},
+		Flush: func(TransformerOptions) StepOutput {
+			return emit(false)
+		},
+		Validate: func(prevStepOut ArgTypes) (ArgTypes, error) {
+			if size <= 0 {
+				return ArgTypes{}, fmt.Errorf("%w [size:%s]", ErrInvalidStepConfig, size)
+			}
+			return simpleMapValidation[IN0, Window[IN0]](prevStepOut)
+		},
+		Reset: func() {
+			watermark = time.Time{}
+			windows = map[int64]*Window[IN0]{}
+		},
+	}
+}
+
+// SessionWindow groups the inputs into sessions, where a session is closed when no input arrives within the gap duration.
+// The time of the inputs is returned by timestampFn and the inputs are expected to arrive in time order.
+// A session is emitted once an input arrives after the gap, or when the input is exhausted.
+func SessionWindow[IN0 any](gap time.Duration, timestampFn func(in IN0) (time.Time, error)) StepWrapper {
+	window := SessionWindowCtx(gap, func(_ context.Context, in IN0) (time.Time, error) {
+		return timestampFn(in)
+	})
+	window.Name = "SessionWindow"
+	return window
+}
+
+// SessionWindowCtx is a variant of [SessionWindow] passing the context of the transformer to the timestamp function
+func SessionWindowCtx[IN0 any](gap time.Duration, timestampFn func(ctx context.Context, in IN0) (time.Time, error)) StepWrapper {
+	var session *Window[IN0]
+	return StepWrapper{
+		Name: "SessionWindowCtx",
+		StepFn: func(in StepInput) StepOutput {
+			ts, err := timestampFn(ctxOf(in), in.Args[0].(IN0))
+			if err != nil {
+				return StepOutput{Error: err}
+			}
+
+			out := StepOutput{Skip: true}
+			if session != nil && !ts.Before(session.End) {
+				out = StepOutput{
+					Args:    Args{*session},
+					ArgsLen: 1,
+				}
+				session = nil
+			}
+
+			if session == nil {
+				session = &Window[IN0]{Start: ts, End: ts.Add(gap)}
+			}
+			if ts.Before(session.Start) {
+				session.Start = ts
+			}
+			if end := ts.Add(gap); end.After(session.End) {
+				session.End = end
+			}
+			session.Items = append(session.Items, in.Args[0].(IN0))
+
+			return out
+		},
+		Flush: func(TransformerOptions) StepOutput {
+			if session == nil {
+				return StepOutput{Skip: true}
+			}
+			out := StepOutput{
+				Args:    Args{*session},
+				ArgsLen: 1,
+			}
+			session = nil
+			return out
+		},
+		Validate: simpleMapValidation[IN0, Window[IN0]],
+		Reset: func() {
+			session = nil
+		},
+	}
+}
+
+// ReduceWindow applies an aggregator to the inputs of each window and passes the aggregated value to the next step.
+// The aggregator state is reset before each window. The aggregated value of GroupBy is it's map of groups.
+func ReduceWindow[IN0 any](reducer ReducerWrapper) StepWrapper {
+	return StepWrapper{
+		Name: "ReduceWindow",
+		StepFn: func(in StepInput) StepOutput {
+			w := in.Args[0].(Window[IN0])
+			if len(w.Items) == 0 {
+				return StepOutput{Skip: true}
+			}
+
+			if reducer.Reset != nil {
+				reducer.Reset()
+			}
+			var out StepOutput
+			for _, item := range w.Items {
+				out = reducer.ReducerFn(StepInput{
+					Args:               Args{item},
+					ArgsLen:            1,
+					TransformerOptions: in.TransformerOptions,
+				})
+				if out.Error != nil {
+					break
+				}
+			}
+			return out
+		},
+		Validate: func(prevStepOut ArgTypes) (ArgTypes, error) {
+			if err := argTypesValidation(prevStepOut, ArgTypes{reflect.TypeFor[Window[IN0]]()}); err != nil {
+				return ArgTypes{}, err
+			}
+			if len(reducer.Name) == 0 || reducer.ReducerFn == nil {
+				return ArgTypes{}, ErrInvalidAggregator
+			}
+			outTypes, err := reducer.Validate(ArgTypes{reflect.TypeFor[IN0]()})
+			if err != nil {
+				return ArgTypes{}, err
+			}
+			return aggregatedTypes(outTypes), nil
+		},
+	}
+}
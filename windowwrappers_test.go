@@ -0,0 +1,300 @@
+package steps
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testEvent struct {
+	At    int
+	Value int
+}
+
+var baseTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func eventTime(in testEvent) (time.Time, error) {
+	return baseTime.Add(time.Duration(in.At) * time.Second), nil
+}
+
+func at(sec int) time.Time {
+	return baseTime.Add(time.Duration(sec) * time.Second)
+}
+
+func events(at ...int) []testEvent {
+	res := make([]testEvent, len(at))
+	for i, a := range at {
+		res[i] = testEvent{At: a, Value: i + 1}
+	}
+	return res
+}
+
+func TestTumblingWindow_Success(t *testing.T) {
+	input := events(0, 3, 9, 10, 12, 31, 25)
+
+	actual := Transform[testEvent](input, WithErrorHandler(expectsError(t, false))).
+		WithSteps(TumblingWindow(10*time.Second, eventTime)).
+		AsSlice()
+
+	expected := []any{
+		Window[testEvent]{Start: at(0), End: at(10), Items: input[0:3]},
+		Window[testEvent]{Start: at(10), End: at(20), Items: input[3:5]},
+		Window[testEvent]{Start: at(30), End: at(40), Items: input[5:6]},
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestTumblingWindow_Failure(t *testing.T) {
+	actual := Transform[testEvent](events(0, 3, 12, 15), WithErrorHandler(expectsError(t, true))).
+		WithSteps(TumblingWindow(10*time.Second, func(in testEvent) (time.Time, error) {
+			if in.At == 15 {
+				return time.Time{}, errors.New("timestamp error")
+			}
+			return eventTime(in)
+		})).
+		AsSlice()
+
+	assert.Len(t, actual, 1)
+}
+
+func TestSlidingWindow_Success(t *testing.T) {
+	input := events(1, 6, 12)
+
+	actual := Transform[testEvent](input, WithErrorHandler(expectsError(t, false))).
+		WithSteps(SlidingWindow(10*time.Second, 5*time.Second, eventTime)).
+		AsSlice()
+
+	expected := []any{
+		Window[testEvent]{Start: at(-5), End: at(5), Items: input[0:1]},
+		Window[testEvent]{Start: at(0), End: at(10), Items: input[0:2]},
+		Window[testEvent]{Start: at(5), End: at(15), Items: input[1:3]},
+		Window[testEvent]{Start: at(10), End: at(20), Items: input[2:3]},
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestSessionWindow_Success(t *testing.T) {
+	input := events(0, 2, 5, 20, 22, 40)
+
+	actual := Transform[testEvent](input, WithErrorHandler(expectsError(t, false))).
+		WithSteps(SessionWindow(5*time.Second, eventTime)).
+		AsSlice()
+
+	expected := []any{
+		Window[testEvent]{Start: at(0), End: at(10), Items: input[0:3]},
+		Window[testEvent]{Start: at(20), End: at(27), Items: input[3:5]},
+		Window[testEvent]{Start: at(40), End: at(45), Items: input[5:6]},
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestSessionWindow_Failure(t *testing.T) {
+	actual := Transform[testEvent](events(0, 20, 22), WithErrorHandler(expectsError(t, true))).
+		WithSteps(SessionWindow(5*time.Second, func(in testEvent) (time.Time, error) {
+			if in.At == 22 {
+				return time.Time{}, errors.New("timestamp error")
+			}
+			return eventTime(in)
+		})).
+		AsSlice()
+
+	assert.Len(t, actual, 1)
+}
+
+func TestWindows_Validate(t *testing.T) {
+	for _, stepWrapper := range []StepWrapper{
+		TumblingWindow(time.Second, eventTime),
+		SlidingWindow(time.Second, time.Second, eventTime),
+		SessionWindow(time.Second, eventTime),
+	} {
+		t.Run(stepWrapper.Name, func(t *testing.T) {
+			actualOut, actualErr := stepWrapper.Validate(ArgTypes{reflect.TypeFor[testEvent]()})
+			assert.Equal(t, ArgTypes{reflect.TypeFor[Window[testEvent]]()}, actualOut)
+			assert.NoError(t, actualErr)
+
+			_, actualErr = stepWrapper.Validate(ArgTypes{reflect.TypeFor[int]()})
+			assert.ErrorIs(t, actualErr, ErrIncompatibleInArgType)
+			assert.ErrorContains(t, actualErr, "[int!=steps.testEvent:1]")
+		})
+	}
+
+	for _, stepWrapper := range []StepWrapper{
+		TumblingWindow(0, eventTime),
+		SlidingWindow(-time.Second, time.Second, eventTime),
+	} {
+		t.Run(stepWrapper.Name+"_invalid_size", func(t *testing.T) {
+			_, actualErr := stepWrapper.Validate(ArgTypes{reflect.TypeFor[testEvent]()})
+			assert.ErrorIs(t, actualErr, ErrInvalidStepConfig)
+		})
+	}
+}
+
+func TestReduceWindow_Success(t *testing.T) {
+	actual := Transform[testEvent](events(0, 3, 12, 15, 18), WithErrorHandler(expectsError(t, false))).
+		WithSteps(
+			TumblingWindow(10*time.Second, eventTime),
+			Map(func(in Window[testEvent]) (Window[int], error) {
+				res := Window[int]{Start: in.Start, End: in.End}
+				for _, e := range in.Items {
+					res.Items = append(res.Items, e.Value)
+				}
+				return res, nil
+			}),
+			ReduceWindow[int](Sum[int]())).
+		AsSlice()
+
+	assert.Equal(t, []any{3, 12}, actual)
+}
+
+func TestReduceWindow_Avg(t *testing.T) {
+	actual := Transform[Window[float64]]([]Window[float64]{{Items: []float64{1, 3}}, {Items: []float64{10, 20}}}, WithErrorHandler(expectsError(t, false))).
+		WithSteps(ReduceWindow[float64](Avg())).
+		AsSlice()
+
+	assert.Equal(t, []any{2.0, 15.0}, actual)
+}
+
+func TestReduceWindow_GroupBy(t *testing.T) {
+	actual := Transform[Window[int]]([]Window[int]{{Items: []int{1, 2, 3}}, {Items: []int{4}}}, WithErrorHandler(expectsError(t, false))).
+		WithSteps(
+			ReduceWindow[int](GroupBy(func(in int) (int, int, error) {
+				return in % 2, in, nil
+			})),
+			Map(func(in map[int][]int) (int, error) {
+				return len(in[1]), nil
+			})).
+		AsSlice()
+
+	assert.Equal(t, []any{2, 0}, actual)
+}
+
+func TestReduceWindow_Failure(t *testing.T) {
+	actual := Transform[Window[int]]([]Window[int]{{Items: []int{1, 2}}, {Items: []int{3, 4}}}, WithErrorHandler(expectsError(t, true))).
+		WithSteps(
+			ReduceWindow[int](Reduce(func(in1, in2 int) (int, error) {
+				if in2 == 4 {
+					return 0, errors.New("reduce error")
+				}
+				return in1 + in2, nil
+			}))).
+		AsSlice()
+
+	assert.Equal(t, []any{3}, actual)
+}
+
+func TestReduceWindow_Validate(t *testing.T) {
+	for _, sc := range []struct {
+		name          string
+		prevStepOut   ArgTypes
+		reducer       ReducerWrapper
+		expectedOut   ArgTypes
+		expectedError error
+	}{
+		{
+			name:        "matching_prev_step_out_type",
+			prevStepOut: ArgTypes{reflect.TypeFor[Window[int]]()},
+			reducer:     Sum[int](),
+			expectedOut: ArgTypes{reflect.TypeFor[int]()},
+		}, {
+			name:        "skip_type_check_when_first_step",
+			prevStepOut: ArgTypes{reflect.TypeFor[SkipFirstArgValidation]()},
+			reducer:     Sum[int](),
+			expectedOut: ArgTypes{reflect.TypeFor[int]()},
+		}, {
+			name:          "different_prev_step_out_type",
+			prevStepOut:   ArgTypes{reflect.TypeFor[int]()},
+			reducer:       Sum[int](),
+			expectedError: ErrIncompatibleInArgType,
+		}, {
+			name:          "different_reducer_in_type",
+			prevStepOut:   ArgTypes{reflect.TypeFor[Window[int]]()},
+			reducer:       Sum[float64](),
+			expectedError: ErrIncompatibleInArgType,
+		}, {
+			name:        "grouping_reducer",
+			prevStepOut: ArgTypes{reflect.TypeFor[Window[int]]()},
+			reducer: GroupBy(func(in int) (string, int, error) {
+				return "", in, nil
+			}),
+			expectedOut: ArgTypes{reflect.TypeFor[map[string][]int]()},
+		}, {
+			name:          "invalid_reducer",
+			prevStepOut:   ArgTypes{reflect.TypeFor[Window[int]]()},
+			reducer:       ReducerWrapper{Name: "invalid"},
+			expectedError: ErrInvalidAggregator,
+		},
+	} {
+		t.Run(sc.name, func(t *testing.T) {
+			actualOut, actualErr := ReduceWindow[int](sc.reducer).Validate(sc.prevStepOut)
+
+			assert.Equal(t, sc.expectedOut, actualOut)
+			if sc.expectedError != nil {
+				assert.ErrorIs(t, actualErr, sc.expectedError)
+			} else {
+				assert.NoError(t, actualErr)
+			}
+		})
+	}
+}
+
+func ExampleTumblingWindow() {
+	type reading struct {
+		At    time.Time
+		Value int
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	res := Transform[reading]([]reading{
+		{start, 1},
+		{start.Add(30 * time.Second), 2},
+		{start.Add(70 * time.Second), 3},
+	}).
+		WithSteps(
+			TumblingWindow(time.Minute, func(in reading) (time.Time, error) {
+				return in.At, nil
+			}),
+			Map(func(in Window[reading]) (string, error) {
+				return fmt.Sprintf("%s:%d", in.Start.Format(time.TimeOnly), len(in.Items)), nil
+			}),
+		).AsSlice()
+
+	fmt.Println(res)
+	// Output: [00:00:00:2 00:01:00:1]
+}
+
+func ExampleSlidingWindow() {
+	fmt.Println("see TumblingWindow")
+	// Output: see TumblingWindow
+}
+
+func ExampleSessionWindow() {
+	res := Transform[int]([]int{1, 2, 3, 10, 11, 20}).
+		WithSteps(
+			SessionWindow(5*time.Second, func(in int) (time.Time, error) {
+				return time.Unix(int64(in), 0), nil
+			}),
+			Map(func(in Window[int]) ([]int, error) {
+				return in.Items, nil
+			}),
+		).AsSlice()
+
+	fmt.Println(res)
+	// Output: [[1 2 3] [10 11] [20]]
+}
+
+func ExampleReduceWindow() {
+	res := Transform[int]([]int{1, 2, 3, 10, 11, 20}).
+		WithSteps(
+			SessionWindow(5*time.Second, func(in int) (time.Time, error) {
+				return time.Unix(int64(in), 0), nil
+			}),
+			ReduceWindow[int](Sum[int]()),
+		).AsSlice()
+
+	fmt.Println(res)
+	// Output: [6 21 20]
+}